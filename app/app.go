@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -16,6 +17,7 @@ import (
 	errors "github.com/cosmos/cosmos-sdk/types"
 	abciTypes "github.com/tendermint/tendermint/abci/types"
 	tmLog "github.com/tendermint/tendermint/libs/log"
+	tmTypes "github.com/tendermint/tendermint/types"
 )
 
 // PlutoApplication implements an ABCI application
@@ -36,6 +38,18 @@ type PlutoApplication struct {
 	// strategy for validator compensation
 	strategy *emtTypes.Strategy
 
+	// txs held back by CheckTx until their nonce is reached
+	queue *txQueue
+
+	// node local settings that can be changed with SetOption
+	optionsMtx   sync.RWMutex
+	minGasPrice  *big.Int              // the lowest gas price accepted by CheckTx, nil accepts any
+	maxTxSize    uint64                // the largest tx accepted by CheckTx
+	setVerbosity func(level int) error // changes the verbosity of the ethereum logs
+
+	// the json-rpc methods forwarded by Query
+	queryFilter *methodFilter
+
 	logger tmLog.Logger
 }
 
@@ -54,15 +68,43 @@ func NewPlutoApplication(backend *ethereum.Backend,
 		getCurrentState: backend.Ethereum().BlockChain().State,
 		checkTxState:    state.Copy(),
 		strategy:        strategy,
+		queue:           newTxQueue(),
+		maxTxSize:       maxTransactionSize,
+		queryFilter:     newMethodFilter(DefaultQueryAllow, DefaultQueryDeny),
 	}
 
 	if err := app.backend.InitEthState(app.Receiver()); err != nil {
 		return nil, err
 	}
 
+	// InitChain isn't called again after a restart
+	if _, err := app.LoadValidators(); err != nil {
+		return nil, err
+	}
+
 	return app, nil
 }
 
+// SetMinGasPrice sets the lowest gas price of the txs accepted by CheckTx.
+// The consensus minimum of the genesis file applies regardless.
+// #unstable
+func (app *PlutoApplication) SetMinGasPrice(price *big.Int) {
+	app.optionsMtx.Lock()
+	defer app.optionsMtx.Unlock()
+
+	app.minGasPrice = price
+}
+
+// SetVerbosityHandler sets the function that changes the verbosity of the
+// ethereum logs for SetOption. The logger of the app itself is not affected.
+// #unstable
+func (app *PlutoApplication) SetVerbosityHandler(setVerbosity func(level int) error) {
+	app.optionsMtx.Lock()
+	defer app.optionsMtx.Unlock()
+
+	app.setVerbosity = setVerbosity
+}
+
 // SetLogger sets the logger for the ethermint application
 func (app *PlutoApplication) SetLogger(log tmLog.Logger) {
 	app.logger = log
@@ -74,15 +116,28 @@ func (app *PlutoApplication) GetLogger() tmLog.Logger {
 
 var bigZero = big.NewInt(0)
 
-// maxTransactionSize is 32KB in order to prevent DOS attacks
+// maxTransactionSize is 32KB in order to prevent DOS attacks. It is the
+// default of the max_tx_size option.
 const maxTransactionSize = 32768
 
+// CodeNonceQueued is returned by CheckTx for a tx whose nonce is ahead of the
+// sender's. The tx is held back and resubmitted once the txs before it arrive.
+const CodeNonceQueued uint32 = 101
+
+// CodeGasPriceTooLow is returned by CheckTx for a tx whose gas price is below
+// the minimum of the node or of the chain
+const CodeGasPriceTooLow uint32 = 102
+
+// CodePruned is returned by Query if the state at the requested height is no
+// longer available
+const CodePruned uint32 = 103
+
 // Info returns information about the last height and app_hash to the tendermint engine
 func (app *PlutoApplication) Info(req abciTypes.RequestInfo) abciTypes.ResponseInfo {
 	blockchain := app.backend.Ethereum().BlockChain()
 	currentBlock := blockchain.CurrentBlock()
 	height := currentBlock.Number()
-	hash := currentBlock.TxHash()
+	hash := ethereum.AppHash(currentBlock)
 
 	app.logger.Debug("Info", "height", height) // nolint: errcheck
 
@@ -109,58 +164,108 @@ func (app *PlutoApplication) Info(req abciTypes.RequestInfo) abciTypes.ResponseI
 func (app *PlutoApplication) SetOption(req abciTypes.RequestSetOption) abciTypes.ResponseSetOption {
 
 	app.logger.Debug("SetOption", "key", req.GetKey(), "value", req.GetValue()) // nolint: errcheck
-	return abciTypes.ResponseSetOption{}
+	if err := app.setOption(req.GetKey(), req.GetValue()); err != nil {
+		return abciTypes.ResponseSetOption{Code: uint32(errors.CodeUnknownRequest), Log: err.Error()}
+	}
+	// nolint: errcheck
+	app.logger.Info("Changed option", "key", req.GetKey(), "value", req.GetValue())
+	return abciTypes.ResponseSetOption{
+		Code: abciTypes.CodeTypeOK,
+		Log:  fmt.Sprintf("%s set to %s", req.GetKey(), req.GetValue()),
+	}
 }
 
 // InitChain initializes the validator set
 // #stable - 0.4.0
 func (app *PlutoApplication) InitChain(req abciTypes.RequestInitChain) abciTypes.ResponseInitChain {
 	app.logger.Debug("InitChain") // nolint: errcheck
-	app.SetValidators(req.Validators)
+	app.backend.InitChain(req.Validators)
+	app.initValidators(req.Validators)
+	if err := app.storeValidators(0); err != nil {
+		app.logger.Error("InitChain: Error storing the validator set", "err", err) // nolint: errcheck
+	}
+	if params := req.ConsensusParams; params != nil && params.BlockSize != nil {
+		blockSize := ethereum.BlockSize{
+			MaxBytes: params.BlockSize.MaxBytes,
+			MaxGas:   params.BlockSize.MaxGas,
+		}
+		if err := app.backend.SetBlockSize(blockSize); err != nil {
+			app.logger.Error("InitChain: Error setting the block size", "err", err) // nolint: errcheck
+		}
+	}
 	return abciTypes.ResponseInitChain{}
 }
 
-// CheckTx checks a transaction is valid but does not mutate the state
+// CheckTx checks a transaction is valid but does not mutate the state.
+// The txs of an envelope are checked one by one, see checkEnvelope. An
+// envelope may not be larger than max_tx_size either.
 func (app *PlutoApplication) CheckTx(txBytes []byte) abciTypes.ResponseCheckTx {
-	tx, err := decodeTx(txBytes)
+	app.optionsMtx.RLock()
+	maxTxSize := app.maxTxSize
+	app.optionsMtx.RUnlock()
+	if uint64(len(txBytes)) > maxTxSize {
+		return abciTypes.ResponseCheckTx{
+			Code: uint32(errors.CodeInternal),
+			Log:  core.ErrOversizedData.Error()}
+	}
+
+	txs, err := ethereum.DecodeTxs(txBytes)
 	if err != nil {
 		// nolint: errcheck
-		app.logger.Debug("CheckTx: Received invalid transaction", "tx", tx)
+		app.logger.Debug("CheckTx: Received invalid transaction", "err", err)
 		return abciTypes.ResponseCheckTx{
 			Code: uint32(errors.CodeInternal),
 			Log:  err.Error(),
 		}
 	}
-	app.logger.Debug("CheckTx: Received valid transaction", "tx", tx) // nolint: errcheck
+	app.logger.Debug("CheckTx: Received valid transaction", "txs", len(txs)) // nolint: errcheck
 
-	return app.validateTx(tx)
+	if !ethereum.IsEnvelope(txBytes) {
+		return app.validateTx(txs[0], true)
+	}
+	return app.checkEnvelope(txs)
 }
 
 // DeliverTx executes a transaction against the latest state
 func (app *PlutoApplication) DeliverTx(txBytes []byte) abciTypes.ResponseDeliverTx {
-	tx, err := decodeTx(txBytes)
+	txs, err := ethereum.DecodeTxs(txBytes)
 	if err != nil {
 		// nolint: errcheck
-		app.logger.Debug("DelivexTx: Received invalid transaction", "tx", tx, "err", err)
+		app.logger.Debug("DelivexTx: Received invalid transaction", "err", err)
 		return abciTypes.ResponseDeliverTx{
 			Code: uint32(errors.CodeInternal),
 			Log:  err.Error(),
 		}
 	}
-	app.logger.Debug("DeliverTx: Received valid transaction", "tx", tx) // nolint: errcheck
+	app.logger.Debug("DeliverTx: Received valid transaction", "txs", len(txs)) // nolint: errcheck
+
+	if !ethereum.IsEnvelope(txBytes) {
+		res, _ := app.deliverTx(txs[0])
+		return res
+	}
+	return app.deliverEnvelope(txs)
+}
 
-	res := app.backend.DeliverTx(tx)
+// deliverTx executes a single ethereum transaction. The result carries the
+// gas, the receipt summary and the tags of the tx if it made it into the
+// block, whose receipt is also returned.
+func (app *PlutoApplication) deliverTx(tx *ethTypes.Transaction) (abciTypes.ResponseDeliverTx, *ethTypes.Receipt) {
+	res, receipt := app.backend.DeliverTx(tx)
+	res.GasWanted = int64(tx.Gas())
+	if receipt != nil {
+		res.GasUsed = int64(receipt.GasUsed)
+		res.Data = encodeReceipt(receipt)
+		res.Tags = txTags(tx, receipt)
+	}
 	if res.IsErr() {
 		// nolint: errcheck
 		app.logger.Error("DeliverTx: Error delivering tx to ethereum backend", "tx", tx,
-			"err", err)
-		return res
+			"err", res.Log)
+		return res, receipt
 	}
-	app.CollectTx(tx)
+	app.deliverValidatorsTx(tx, receipt)
 
-	return abciTypes.ResponseDeliverTx{
-		Code: abciTypes.CodeTypeOK,
-	}
+	return res, receipt
 }
 
 // BeginBlock starts a new Ethereum block
@@ -169,27 +274,72 @@ func (app *PlutoApplication) BeginBlock(beginBlock abciTypes.RequestBeginBlock)
 	app.logger.Debug("BeginBlock") // nolint: errcheck
 	header := beginBlock.GetHeader()
 	// update the eth header with the tendermint header!br0ken!!
-	app.backend.UpdateHeaderWithTimeInfo(&header)
-	return abciTypes.ResponseBeginBlock{}
+	app.backend.UpdateHeaderWithTimeInfo(&header, beginBlock.GetHash())
+
+	// the proposer authors the block, unless a reward strategy picks
+	// another receiver
+	if app.strategy != nil {
+		app.strategy.BeginBlock(beginBlock)
+		if app.strategy.MinerRewardStrategy != nil {
+			app.backend.SetCoinbase(app.Receiver())
+		}
+	}
+
+	app.backend.SetLastCommit(beginBlock.GetLastCommitInfo().Votes)
+
+	// the validator contract alone governs the validator set, jailing and
+	// slashing would be overridden by its diff
+	if app.backend.Params().ValidatorContract != nil {
+		return abciTypes.ResponseBeginBlock{}
+	}
+	tags := app.handleVotes(beginBlock.GetLastCommitInfo().Votes)
+	for _, evidence := range beginBlock.GetByzantineValidators() {
+		if evidence.Type != tmTypes.ABCIEvidenceTypeDuplicateVote {
+			continue
+		}
+		tags = append(tags, app.slashDoubleSign(evidence)...)
+	}
+	return abciTypes.ResponseBeginBlock{Tags: tags}
 }
 
 // EndBlock accumulates rewards for the validators and updates them
 func (app *PlutoApplication) EndBlock(endBlock abciTypes.RequestEndBlock) abciTypes.ResponseEndBlock {
 
 	app.logger.Debug("EndBlock", "height", endBlock.GetHeight()) // nolint: errcheck
-	app.backend.AccumulateRewards(app.strategy)
-	return app.GetUpdatedValidators()
+	if err := app.backend.AccumulateRewards(); err != nil {
+		app.logger.Error("EndBlock: Error accumulating rewards", "err", err) // nolint: errcheck
+	}
+	var updates []abciTypes.ValidatorUpdate
+	if app.backend.Params().ValidatorContract != nil {
+		updates = app.contractValidatorUpdates()
+	} else {
+		updates = app.backend.EndBlock()
+	}
+	app.UpdateValidators(updates)
+	app.endValidatorsBlock(endBlock.GetHeight())
+	res := app.GetUpdatedValidators()
+	if app.strategy == nil {
+		// nothing tracks the validator set, the keeper has already stored
+		// the new power, so its updates must reach tendermint as they are
+		res.ValidatorUpdates = updates
+	}
+	res.ConsensusParamUpdates = app.updateMaxGas(endBlock.GetHeight())
+	return res
 }
 
 // Commit commits the block and returns a hash of the current state
 func (app *PlutoApplication) Commit() abciTypes.ResponseCommit {
 	app.logger.Debug("Commit") // nolint: errcheck
-	blockHash, err := app.backend.Commit(app.Receiver())
+	appHash, err := app.backend.Commit(app.Receiver())
 	if err != nil {
 		// nolint: errcheck
 		app.logger.Error("Error getting latest ethereum state", "err", err)
 		return abciTypes.ResponseCommit{}
 	}
+	number := app.backend.Ethereum().BlockChain().CurrentBlock().NumberU64()
+	if err := app.storeValidators(number); err != nil {
+		app.logger.Error("Error storing the validator set", "err", err) // nolint: errcheck
+	}
 	state, err := app.getCurrentState()
 	if err != nil {
 		app.logger.Error("Error getting latest state", "err", err) // nolint: errcheck
@@ -197,19 +347,38 @@ func (app *PlutoApplication) Commit() abciTypes.ResponseCommit {
 	}
 
 	app.checkTxState = state.Copy()
+	for _, tx := range app.queue.ready(app.checkTxState) {
+		app.promote(tx)
+	}
 	return abciTypes.ResponseCommit{
-		Data: blockHash[:],
+		Data: appHash[:],
 	}
 }
 
 // Query queries the state of the EthermintApplication
 func (app *PlutoApplication) Query(query abciTypes.RequestQuery) abciTypes.ResponseQuery {
 	app.logger.Debug("Query") // nolint: errcheck
+	if res, ok := app.queryPath(query); ok {
+		return res
+	}
+
 	var in jsonRequest
 	if err := json.Unmarshal(query.Data, &in); err != nil {
 		return abciTypes.ResponseQuery{Code: uint32(errors.CodeInternal),
 			Log: err.Error()}
 	}
+	if !app.queryFilter.allowed(in.Method) {
+		return abciTypes.ResponseQuery{Code: uint32(errors.CodeUnauthorized),
+			Log: fmt.Sprintf("method %q is not allowed", in.Method)}
+	}
+	if err := setBlockParam(&in, query.Height); err != nil {
+		return abciTypes.ResponseQuery{Code: uint32(errors.CodeUnknownRequest),
+			Log: err.Error()}
+	}
+	block, errRes := app.queryBlock(query.Height)
+	if errRes != nil {
+		return *errRes
+	}
 	var result interface{}
 	if err := app.rpcClient.Call(&result, in.Method, in.Params...); err != nil {
 		return abciTypes.ResponseQuery{Code: uint32(errors.CodeInternal),
@@ -220,17 +389,23 @@ func (app *PlutoApplication) Query(query abciTypes.RequestQuery) abciTypes.Respo
 		return abciTypes.ResponseQuery{Code: uint32(errors.CodeInternal),
 			Log: err.Error()}
 	}
-	return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Value: bytes}
+	return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Value: bytes,
+		Height: block.Number().Int64()}
 }
 
 //-------------------------------------------------------
 
 // validateTx checks the validity of a tx against the blockchain's current state.
-// it duplicates the logic in ethereum's tx_pool
-func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction) abciTypes.ResponseCheckTx {
+// it duplicates the logic in ethereum's tx_pool. Txs ahead of the sender's nonce
+// are rejected unless queue is set.
+func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction, queue bool) abciTypes.ResponseCheckTx {
+
+	app.optionsMtx.RLock()
+	maxTxSize, nodeMinGasPrice := app.maxTxSize, app.minGasPrice
+	app.optionsMtx.RUnlock()
 
-	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
-	if tx.Size() > maxTransactionSize {
+	// Heuristic limit, reject transactions over max_tx_size to prevent DOS attacks
+	if uint64(tx.Size()) > maxTxSize {
 		return abciTypes.ResponseCheckTx{
 			Code: uint32(errors.CodeInternal),
 			Log:  core.ErrOversizedData.Error()}
@@ -258,6 +433,19 @@ func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction) abciTypes.Resp
 			Log:  core.ErrNegativeValue.Error()}
 	}
 
+	// Make sure the gas price is high enough for this node and the chain
+	minGasPrice := app.backend.Params().GetMinGasPrice()
+	if nodeMinGasPrice != nil && nodeMinGasPrice.Cmp(minGasPrice) > 0 {
+		minGasPrice = nodeMinGasPrice
+	}
+	if tx.GasPrice().Cmp(minGasPrice) < 0 {
+		return abciTypes.ResponseCheckTx{
+			Code: CodeGasPriceTooLow,
+			Log: fmt.Sprintf(
+				"Gas price %s below the minimum of %s",
+				tx.GasPrice(), minGasPrice)}
+	}
+
 	currentState := app.checkTxState
 
 	// Make sure the account exist - cant send from non-existing account.
@@ -275,9 +463,9 @@ func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction) abciTypes.Resp
 			Log:  core.ErrGasLimitReached.Error()}
 	}
 
-	// Check if nonce is not strictly increasing
+	// Check if nonce is not strictly increasing.
 	nonce := currentState.GetNonce(from)
-	if nonce != tx.Nonce() {
+	if nonce > tx.Nonce() {
 		return abciTypes.ResponseCheckTx{
 			Code: uint32(errors.CodeInvalidSequence),
 			Log: fmt.Sprintf(
@@ -305,6 +493,30 @@ func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction) abciTypes.Resp
 			Log:  err.Error()}
 	}
 
+	// Txs ahead of the sender's nonce are queued, if the sender can pay for
+	// them now
+	if nonce < tx.Nonce() {
+		if !queue {
+			return abciTypes.ResponseCheckTx{
+				Code: uint32(errors.CodeInvalidSequence),
+				Log: fmt.Sprintf(
+					"Nonce too high. Expected %d Got %d",
+					nonce, tx.Nonce())}
+		}
+		if !app.queue.add(from, nonce, tx) {
+			return abciTypes.ResponseCheckTx{
+				Code: uint32(errors.CodeInvalidSequence),
+				Log: fmt.Sprintf(
+					"Nonce too far ahead or queue full. Expected %d Got %d",
+					nonce, tx.Nonce())}
+		}
+		return abciTypes.ResponseCheckTx{
+			Code: CodeNonceQueued,
+			Log: fmt.Sprintf(
+				"Nonce ahead, tx queued. Expected %d Got %d",
+				nonce, tx.Nonce())}
+	}
+
 	// Update ether balances
 	// amount + gasprice * gaslimit
 	currentState.SubBalance(from, tx.Cost())
@@ -315,5 +527,9 @@ func (app *PlutoApplication) validateTx(tx *ethTypes.Transaction) abciTypes.Resp
 	}
 	currentState.SetNonce(from, tx.Nonce()+1)
 
-	return abciTypes.ResponseCheckTx{Code: abciTypes.CodeTypeOK}
+	if next := app.queue.pop(from, tx.Nonce()+1); next != nil {
+		app.promote(next)
+	}
+
+	return abciTypes.ResponseCheckTx{Code: abciTypes.CodeTypeOK, GasWanted: int64(tx.Gas())}
 }
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	tmLog "github.com/tendermint/tendermint/libs/log"
+
+	"github.com/zhuzeyu/pluto/ethereum"
+	plutoUtils "github.com/zhuzeyu/pluto/utils"
+)
+
+const testNodeName = "ethermint"
+
+// testKey is funded by the test genesis
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+const testGenesis = `{
+	"config": {
+		"chainId": 15,
+		"homesteadBlock": 0,
+		"eip155Block": 0,
+		"eip158Block": 0
+	},
+	"difficulty": "0x40",
+	"gasLimit": "0x8000000",
+	"alloc": {
+		"0x7eff122b94897ea5b0e2a9abf47b86337fafebdc": {
+			"balance": "10000000000000000000000000000000000"
+		},
+		"0x71562b71999873db5b286df957af199ec94617f7": {
+			"balance": "1000000000000000000000"
+		}
+	}
+}`
+
+// writeTestGenesis does what `pluto init` does with a small genesis file
+func writeTestGenesis(t *testing.T, dataDir string) {
+	genesisPath := filepath.Join(dataDir, "genesis.json")
+	if err := ioutil.WriteFile(genesisPath, []byte(testGenesis), 0600); err != nil {
+		t.Fatal(err)
+	}
+	chainDb, err := ethdb.NewLDBDatabase(filepath.Join(dataDir, testNodeName, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chainDb.Close()
+
+	// a block reward makes every block change the state root
+	genesis, plutoParams := plutoUtils.ReadGenesis(genesisPath)
+	plutoParams.BlockReward = (*math.HexOrDecimal256)(big.NewInt(params.Ether))
+	if err := ethereum.SeedSupply(genesis); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := core.SetupGenesisBlock(chainDb, genesis); err != nil {
+		t.Fatal(err)
+	}
+	if err := ethereum.WriteParams(chainDb, plutoParams); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestApp starts an ethereum node on the data dir without networking and
+// creates the app on top of it
+func newTestApp(t *testing.T, dataDir string) (*ethereum.Node, *PlutoApplication) {
+	stack, err := ethereum.New(&node.Config{
+		Name:    testNodeName,
+		DataDir: dataDir,
+		NoUSB:   true,
+		P2P:     p2p.Config{NoDiscovery: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethConfig := eth.DefaultConfig
+	ethConfig.Ethash.PowMode = ethash.ModeFullFake
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return ethereum.NewBackend(ctx, &ethConfig, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var backend *ethereum.Backend
+	if err := stack.Service(&backend); err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewPlutoApplication(backend, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.SetLogger(tmLog.NewNopLogger())
+	return stack, app
+}
+
+// commitBlock runs an empty block through the app and returns its app hash
+func commitBlock(t *testing.T, app *PlutoApplication, height int64) []byte {
+	app.BeginBlock(abciTypes.RequestBeginBlock{
+		Hash: crypto.Keccak256([]byte{byte(height)}),
+		Header: abciTypes.Header{
+			Height: height,
+			Time:   time.Unix(1500000000+height, 0),
+		},
+	})
+	app.EndBlock(abciTypes.RequestEndBlock{Height: height})
+	appHash := app.Commit().Data
+	if len(appHash) == 0 {
+		t.Fatalf("block %d not committed", height)
+	}
+	return appHash
+}
+
+func TestAppHashAcrossRestart(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "pluto-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir) // nolint: errcheck
+	writeTestGenesis(t, dataDir)
+
+	stack, app := newTestApp(t, dataDir)
+	info := app.Info(abciTypes.RequestInfo{})
+	if info.LastBlockHeight != 0 || len(info.LastBlockAppHash) != 0 {
+		t.Fatalf("expected a fresh chain, got height %d and app hash %X",
+			info.LastBlockHeight, info.LastBlockAppHash)
+	}
+
+	app.InitChain(abciTypes.RequestInitChain{})
+	var appHash []byte
+	for height := int64(1); height <= 3; height++ {
+		prevHash := appHash
+		appHash = commitBlock(t, app, height)
+		if bytes.Equal(appHash, prevHash) {
+			t.Fatalf("block %d didn't change the app hash", height)
+		}
+	}
+
+	// the app hash is the state root, not the block hash
+	block := app.backend.Ethereum().BlockChain().CurrentBlock()
+	if !bytes.Equal(appHash, block.Root().Bytes()) {
+		t.Errorf("expected app hash %X to be the state root %X", appHash, block.Root())
+	}
+	info = app.Info(abciTypes.RequestInfo{})
+	if info.LastBlockHeight != 3 || !bytes.Equal(info.LastBlockAppHash, appHash) {
+		t.Errorf("expected height 3 and app hash %X, got height %d and app hash %X",
+			appHash, info.LastBlockHeight, info.LastBlockAppHash)
+	}
+	if err := stack.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// tendermint compares the app hash of the handshake with the one of the
+	// last commit
+	stack, app = newTestApp(t, dataDir)
+	defer stack.Stop() // nolint: errcheck
+	info = app.Info(abciTypes.RequestInfo{})
+	if info.LastBlockHeight != 3 || !bytes.Equal(info.LastBlockAppHash, appHash) {
+		t.Fatalf("after restart expected height 3 and app hash %X, got height %d and app hash %X",
+			appHash, info.LastBlockHeight, info.LastBlockAppHash)
+	}
+
+	// the restarted app builds on the same chain
+	appHash = commitBlock(t, app, 4)
+	info = app.Info(abciTypes.RequestInfo{})
+	if info.LastBlockHeight != 4 || !bytes.Equal(info.LastBlockAppHash, appHash) {
+		t.Errorf("expected height 4 and app hash %X, got height %d and app hash %X",
+			appHash, info.LastBlockHeight, info.LastBlockAppHash)
+	}
+}
+
+func TestSingleEngine(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "pluto-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir) // nolint: errcheck
+	writeTestGenesis(t, dataDir)
+
+	stack, app := newTestApp(t, dataDir)
+	defer stack.Stop() // nolint: errcheck
+	app.InitChain(abciTypes.RequestInitChain{})
+	commitBlock(t, app, 1)
+
+	// ethash is replaced everywhere go-ethereum looks for the engine
+	blockchain := app.backend.Ethereum().BlockChain()
+	for name, engine := range map[string]interface{}{
+		"service":    app.backend.Ethereum().Engine(),
+		"blockchain": blockchain.Engine(),
+	} {
+		if plutoEngine, ok := engine.(*ethereum.Engine); !ok || plutoEngine != app.backend.Engine() {
+			t.Errorf("%s: expected the pluto engine, got %T", name, engine)
+		}
+	}
+	if err := blockchain.Engine().VerifyHeader(blockchain, blockchain.CurrentHeader(), false); err != nil {
+		t.Errorf("expected the committed header to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+//----------------------------------------------------------------------
+// An envelope carries several ethereum txs in one tendermint tx, see
+// ethereum.EncodeTxs. CheckTx and DeliverTx report the result of every tx
+// RLP encoded in their Data, and the gas and tags of all txs.
+
+// TxResult is the result of one tx of an envelope. The receipt is only
+// filled in by DeliverTx.
+// #unstable
+type TxResult struct {
+	Hash    common.Hash
+	Code    uint32
+	Log     string
+	Receipt ReceiptSummary
+}
+
+// checkEnvelope checks the txs of an envelope in order, like deliverEnvelope
+// executes them. It only fails if none of them is valid. An invalid tx leaves
+// the check state alone, so the valid txs before and after it stay applied.
+func (app *PlutoApplication) checkEnvelope(txs []*ethTypes.Transaction) abciTypes.ResponseCheckTx {
+	results := make([]TxResult, 0, len(txs))
+	failed := 0
+	var firstErr abciTypes.ResponseCheckTx
+	gasWanted := int64(0)
+	for i, tx := range txs {
+		res := app.validateTx(tx, false)
+		results = append(results, TxResult{
+			Hash:    tx.Hash(),
+			Code:    res.Code,
+			Log:     res.Log,
+			Receipt: newReceiptSummary(nil),
+		})
+		if res.IsErr() {
+			if failed == 0 {
+				firstErr = res
+				firstErr.Log = fmt.Sprintf("tx %d: %s", i, res.Log)
+			}
+			failed++
+			continue
+		}
+		gasWanted += res.GasWanted
+	}
+
+	if failed == len(txs) {
+		firstErr.Data = encodeTxResults(results)
+		return firstErr
+	}
+	res := abciTypes.ResponseCheckTx{
+		Code:      abciTypes.CodeTypeOK,
+		Data:      encodeTxResults(results),
+		GasWanted: gasWanted,
+	}
+	if failed > 0 {
+		res.Log = fmt.Sprintf("%d of %d txs failed", failed, len(txs))
+	}
+	return res
+}
+
+// deliverEnvelope executes the txs of an envelope in order. It only fails if
+// none of them could be executed.
+func (app *PlutoApplication) deliverEnvelope(txs []*ethTypes.Transaction) abciTypes.ResponseDeliverTx {
+	results := make([]TxResult, 0, len(txs))
+	failed := 0
+	var firstErr abciTypes.ResponseDeliverTx
+	var gasWanted, gasUsed int64
+	var tags []cmn.KVPair
+	for _, tx := range txs {
+		res, receipt := app.deliverTx(tx)
+		results = append(results, TxResult{
+			Hash:    tx.Hash(),
+			Code:    res.Code,
+			Log:     res.Log,
+			Receipt: newReceiptSummary(receipt),
+		})
+		gasWanted += res.GasWanted
+		gasUsed += res.GasUsed
+		tags = append(tags, res.Tags...)
+		if res.IsErr() {
+			if failed == 0 {
+				firstErr = res
+			}
+			failed++
+		}
+	}
+
+	if failed == len(txs) {
+		firstErr.Data = encodeTxResults(results)
+		firstErr.GasWanted = gasWanted
+		firstErr.GasUsed = gasUsed
+		firstErr.Tags = tags
+		return firstErr
+	}
+	res := abciTypes.ResponseDeliverTx{
+		Code:      abciTypes.CodeTypeOK,
+		Data:      encodeTxResults(results),
+		GasWanted: gasWanted,
+		GasUsed:   gasUsed,
+		Tags:      tags,
+	}
+	if failed > 0 {
+		res.Log = fmt.Sprintf("%d of %d txs failed", failed, len(txs))
+	}
+	return res
+}
+
+// encodeTxResults RLP encodes the results, which can't fail for plain values
+func encodeTxResults(results []TxResult) []byte {
+	data, _ := rlp.EncodeToBytes(results) // nolint: errcheck
+	return data
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/zhuzeyu/pluto/ethereum"
+)
+
+// signTestTx signs a transfer from testKey
+func signTestTx(t *testing.T, nonce uint64) *ethTypes.Transaction {
+	tx := ethTypes.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1),
+		21000, big.NewInt(1), nil)
+	tx, err := ethTypes.SignTx(tx, ethTypes.NewEIP155Signer(big.NewInt(15)), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestCheckEnvelope(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "pluto-envelope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir) // nolint: errcheck
+	writeTestGenesis(t, dataDir)
+	stack, app := newTestApp(t, dataDir)
+	defer stack.Stop() // nolint: errcheck
+	from := crypto.PubkeyToAddress(testKey.PublicKey)
+
+	tests := []struct {
+		name   string
+		nonces []uint64
+		ok     bool
+		valid  []bool
+		nonce  uint64 // of the sender in the check state afterwards
+	}{
+		{"all valid", []uint64{0, 1}, true, []bool{true, true}, 2},
+		{"one invalid", []uint64{2, 9, 3}, true, []bool{true, false, true}, 4},
+		{"all invalid", []uint64{0, 9}, false, []bool{false, false}, 4},
+	}
+	for _, test := range tests {
+		txs := make([]*ethTypes.Transaction, len(test.nonces))
+		for i, nonce := range test.nonces {
+			txs[i] = signTestTx(t, nonce)
+		}
+		res := app.checkEnvelope(txs)
+		if res.IsOK() != test.ok {
+			t.Errorf("%s: expected ok %v, got %d %s", test.name, test.ok, res.Code, res.Log)
+		}
+
+		var results []TxResult
+		if err := rlp.DecodeBytes(res.Data, &results); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(results) != len(test.valid) {
+			t.Fatalf("%s: expected %d results, got %d", test.name, len(test.valid), len(results))
+		}
+		for i, result := range results {
+			if (result.Code == abciTypes.CodeTypeOK) != test.valid[i] {
+				t.Errorf("%s: tx %d: expected valid %v, got %d %s",
+					test.name, i, test.valid[i], result.Code, result.Log)
+			}
+		}
+		if nonce := app.checkTxState.GetNonce(from); nonce != test.nonce {
+			t.Errorf("%s: expected nonce %d, got %d", test.name, test.nonce, nonce)
+		}
+	}
+}
+
+func TestCheckTxSize(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "pluto-envelope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir) // nolint: errcheck
+	writeTestGenesis(t, dataDir)
+	stack, app := newTestApp(t, dataDir)
+	defer stack.Stop() // nolint: errcheck
+
+	// every tx fits, the envelope of both doesn't
+	txs := []*ethTypes.Transaction{signTestTx(t, 0), signTestTx(t, 1)}
+	app.maxTxSize = uint64(txs[0].Size())
+	envelope, err := ethereum.EncodeTxs(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := app.CheckTx(envelope); res.Log != core.ErrOversizedData.Error() {
+		t.Errorf("expected the envelope to be too large, got %d %s", res.Code, res.Log)
+	}
+	for _, tx := range txs {
+		data, err := ethereum.EncodeTxs([]*ethTypes.Transaction{tx})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := app.CheckTx(data); res.IsErr() {
+			t.Errorf("expected tx %d to be accepted, got %d %s", tx.Nonce(), res.Code, res.Log)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------
+// Query forwards json-rpc requests to the in-process geth, which serves
+// every api, including the ones that send txs or use the keys of the node.
+// Only the methods passing the filter are forwarded. A pattern is a method
+// name or a prefix ending with "*".
+
+var (
+	// DefaultQueryAllow are the methods that Query forwards by default. They
+	// only read the chain and change nothing on the node.
+	// #unstable
+	DefaultQueryAllow = []string{
+		"eth_blockNumber",
+		"eth_call",
+		"eth_chainId",
+		"eth_estimateGas",
+		"eth_gasPrice",
+		"eth_getBalance",
+		"eth_getBlockByHash",
+		"eth_getBlockByNumber",
+		"eth_getBlockTransactionCountByHash",
+		"eth_getBlockTransactionCountByNumber",
+		"eth_getCode",
+		"eth_getLogs",
+		"eth_getStorageAt",
+		"eth_getTransactionByBlockHashAndIndex",
+		"eth_getTransactionByBlockNumberAndIndex",
+		"eth_getTransactionByHash",
+		"eth_getTransactionCount",
+		"eth_getTransactionReceipt",
+		"eth_getUncleByBlockHashAndIndex",
+		"eth_getUncleByBlockNumberAndIndex",
+		"eth_getUncleCountByBlockHash",
+		"eth_getUncleCountByBlockNumber",
+		"eth_protocolVersion",
+		"eth_syncing",
+		"net_listening",
+		"net_peerCount",
+		"net_version",
+		"pluto_*",
+		"web3_clientVersion",
+		"web3_sha3",
+	}
+
+	// DefaultQueryDeny are the methods that Query never forwards by default,
+	// even if the allow list is widened: those that send or sign txs, expose
+	// the accounts of the node or install filters on it
+	// #unstable
+	DefaultQueryDeny = []string{
+		"eth_accounts",
+		"eth_coinbase",
+		"eth_getFilterChanges",
+		"eth_getFilterLogs",
+		"eth_newBlockFilter",
+		"eth_newFilter",
+		"eth_newPendingTransactionFilter",
+		"eth_resend",
+		"eth_sendTransaction",
+		"eth_sendRawTransaction",
+		"eth_sign",
+		"eth_signTransaction",
+		"eth_submitHashrate",
+		"eth_submitWork",
+		"eth_uninstallFilter",
+	}
+)
+
+// methodFilter decides which json-rpc methods Query forwards
+type methodFilter struct {
+	mtx   sync.RWMutex
+	allow []string
+	deny  []string
+}
+
+func newMethodFilter(allow, deny []string) *methodFilter {
+	return &methodFilter{allow: allow, deny: deny}
+}
+
+// set replaces the patterns of the filter
+func (f *methodFilter) set(allow, deny []string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.allow = allow
+	f.deny = deny
+}
+
+// setAllow replaces the allow patterns of the filter
+func (f *methodFilter) setAllow(allow []string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.allow = allow
+}
+
+// setDeny replaces the deny patterns of the filter
+func (f *methodFilter) setDeny(deny []string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.deny = deny
+}
+
+// allowed tells if a method matches an allow pattern and no deny pattern
+func (f *methodFilter) allowed(method string) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return matchMethod(f.allow, method) && !matchMethod(f.deny, method)
+}
+
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == method {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQueryMethods sets the patterns of the json-rpc methods that Query
+// forwards: the methods matching allow and not matching deny
+// #unstable
+func (app *PlutoApplication) SetQueryMethods(allow, deny []string) {
+	app.queryFilter.set(allow, deny)
+}
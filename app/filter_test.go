@@ -0,0 +1,51 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestMatchMethod(t *testing.T) {
+	patterns := []string{"eth_getBalance", "net_*"}
+	cases := map[string]bool{
+		"eth_getBalance":  true,
+		"eth_getBalances": false,
+		"eth_call":        false,
+		"net_version":     true,
+		"net_":            true,
+		"web3_net_x":      false,
+	}
+	for method, expected := range cases {
+		if matchMethod(patterns, method) != expected {
+			t.Errorf("%s: expected match %v", method, expected)
+		}
+	}
+}
+
+func TestMethodFilter(t *testing.T) {
+	f := newMethodFilter(DefaultQueryAllow, DefaultQueryDeny)
+	for _, method := range []string{"eth_getBalance", "eth_call", "eth_getBlockByNumber",
+		"net_version", "pluto_totalSupply"} {
+
+		if !f.allowed(method) {
+			t.Errorf("expected %s to be allowed", method)
+		}
+	}
+	for _, method := range []string{"eth_sendRawTransaction", "eth_accounts", "eth_resend",
+		"eth_newFilter", "eth_uninstallFilter", "personal_sign", "admin_addPeer"} {
+
+		if f.allowed(method) {
+			t.Errorf("expected %s to be denied", method)
+		}
+	}
+
+	// the deny list still applies if the allow list is widened
+	f.setAllow([]string{"eth_*"})
+	if f.allowed("eth_resend") {
+		t.Error("expected eth_resend to be denied")
+	}
+
+	f.setDeny([]string{"eth_call"})
+	if f.allowed("eth_call") {
+		t.Error("expected eth_call to be denied")
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------
+// SetOption tunes node local settings at runtime. None of them affect
+// consensus, so validators can differ.
+
+// SetOption keys
+const (
+	OptionMinGasPrice = "min_gas_price" // lowest gas price in wei accepted by CheckTx, 0 accepts any
+	OptionMaxTxSize   = "max_tx_size"   // largest tx or envelope in bytes accepted by CheckTx
+	OptionVerbosity   = "verbosity"     // verbosity of the ethereum logs, 0 to 5
+	OptionQueryAllow  = "query_allow"   // comma separated json-rpc methods Query forwards
+	OptionQueryDeny   = "query_deny"    // comma separated json-rpc methods Query never forwards
+)
+
+// maxVerbosity is the most verbose ethereum log level (trace)
+const maxVerbosity = 5
+
+// setOption validates and applies an option
+func (app *PlutoApplication) setOption(key, value string) error {
+	switch key {
+	case OptionMinGasPrice:
+		price, ok := new(big.Int).SetString(value, 10)
+		if !ok || price.Sign() < 0 {
+			return fmt.Errorf("invalid %s %q: want a non-negative number of wei", key, value)
+		}
+		app.SetMinGasPrice(price)
+	case OptionMaxTxSize:
+		size, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || size == 0 {
+			return fmt.Errorf("invalid %s %q: want a positive number of bytes", key, value)
+		}
+		app.optionsMtx.Lock()
+		app.maxTxSize = size
+		app.optionsMtx.Unlock()
+	case OptionVerbosity:
+		level, err := strconv.Atoi(value)
+		if err != nil || level < 0 || level > maxVerbosity {
+			return fmt.Errorf("invalid %s %q: want 0 to %d", key, value, maxVerbosity)
+		}
+		app.optionsMtx.RLock()
+		setVerbosity := app.setVerbosity
+		app.optionsMtx.RUnlock()
+		if setVerbosity == nil {
+			return fmt.Errorf("%s can't be changed on this node", key)
+		}
+		return setVerbosity(level)
+	case OptionQueryAllow:
+		app.queryFilter.setAllow(SplitMethods(value))
+	case OptionQueryDeny:
+		app.queryFilter.setDeny(SplitMethods(value))
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return nil
+}
+
+// SplitMethods splits a comma separated list of json-rpc methods, dropping
+// empty entries
+// #unstable
+func SplitMethods(list string) []string {
+	var methods []string
+	for _, method := range strings.Split(list, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	tmLog "github.com/tendermint/tendermint/libs/log"
+)
+
+func TestSetOption(t *testing.T) {
+	var verbosity int
+	minGasPrice := func(app *PlutoApplication) string { return fmt.Sprint(app.minGasPrice) }
+	maxTxSize := func(app *PlutoApplication) string { return fmt.Sprint(app.maxTxSize) }
+	level := func(app *PlutoApplication) string { return fmt.Sprint(verbosity) }
+	allowed := func(method string) func(app *PlutoApplication) string {
+		return func(app *PlutoApplication) string { return fmt.Sprint(app.queryFilter.allowed(method)) }
+	}
+
+	tests := []struct {
+		key, value string
+		ok         bool
+		get        func(app *PlutoApplication) string
+		want       string // after the option, the default if it fails
+	}{
+		{OptionMinGasPrice, "20000000000", true, minGasPrice, "20000000000"},
+		{OptionMinGasPrice, "0", true, minGasPrice, "0"},
+		{OptionMinGasPrice, "-1", false, minGasPrice, "<nil>"},
+		{OptionMinGasPrice, "1gwei", false, minGasPrice, "<nil>"},
+		{OptionMaxTxSize, "1024", true, maxTxSize, "1024"},
+		{OptionMaxTxSize, "0", false, maxTxSize, fmt.Sprint(maxTransactionSize)},
+		{OptionMaxTxSize, "-5", false, maxTxSize, fmt.Sprint(maxTransactionSize)},
+		{OptionVerbosity, "5", true, level, "5"},
+		{OptionVerbosity, "0", true, level, "0"},
+		{OptionVerbosity, "6", false, level, "3"},
+		{OptionVerbosity, "debug", false, level, "3"},
+		{OptionQueryAllow, "eth_blockNumber, eth_accounts", true, allowed("eth_blockNumber"), "true"},
+		{OptionQueryAllow, "eth_blockNumber, eth_accounts", true, allowed("eth_accounts"), "false"},
+		{OptionQueryAllow, "eth_blockNumber", true, allowed("eth_call"), "false"},
+		{OptionQueryAllow, "", true, allowed("eth_blockNumber"), "false"},
+		{OptionQueryDeny, "eth_call,,", true, allowed("eth_call"), "false"},
+		{OptionQueryDeny, "", true, allowed("eth_accounts"), "false"},
+		{"unknown", "1", false, minGasPrice, "<nil>"},
+	}
+	for _, test := range tests {
+		verbosity = 3
+		app := &PlutoApplication{
+			maxTxSize:   maxTransactionSize,
+			queryFilter: newMethodFilter(DefaultQueryAllow, DefaultQueryDeny),
+			logger:      tmLog.NewNopLogger(),
+		}
+		app.SetVerbosityHandler(func(level int) error {
+			verbosity = level
+			return nil
+		})
+
+		res := app.SetOption(abciTypes.RequestSetOption{Key: test.key, Value: test.value})
+		if ok := res.Code == abciTypes.CodeTypeOK; ok != test.ok {
+			t.Errorf("%s=%q: expected ok %v, got %v", test.key, test.value, test.ok, res)
+			continue
+		}
+		if got := test.get(app); got != test.want {
+			t.Errorf("%s=%q: expected %s, got %s", test.key, test.value, test.want, got)
+		}
+	}
+}
+
+func TestSetVerbosityWithoutHandler(t *testing.T) {
+	app := &PlutoApplication{logger: tmLog.NewNopLogger()}
+	if res := app.SetOption(abciTypes.RequestSetOption{Key: OptionVerbosity, Value: "4"}); res.Code == abciTypes.CodeTypeOK {
+		t.Errorf("expected verbosity to be rejected without a handler, got %v", res)
+	}
+}
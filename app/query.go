@@ -0,0 +1,369 @@
+package app
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	errors "github.com/cosmos/cosmos-sdk/types"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+
+	"github.com/zhuzeyu/pluto/ethereum"
+)
+
+//----------------------------------------------------------------------
+// Query paths answered from the committed state. Addresses, slots and hashes
+// are hex encoded, with or without 0x prefix.
+//
+//	/supply                  RLP encoded total supply of ether
+//	/account/<addr>          RLP encoded account as stored in the state trie:
+//	                         [nonce, balance, storage root, code hash],
+//	                         empty if the account doesn't exist
+//	/storage/<addr>/<slot>   32 byte value of the storage slot
+//	/code/<addr>             code of the account
+//	/receipt/<txhash>        RLP encoded ReceiptSummary, empty if unknown
+//
+// With Prove set, account and storage queries come with merkle proofs, see
+// ProofOpAccount. The height of the response is the block whose state is
+// queried. Its state root is the app hash of the next tendermint header.
+//
+// Any other query is forwarded to the ethereum json-rpc. The Height of a query
+// selects the state it reads, see queryBlock. Only the methods with a block
+// parameter can be queried at a height other than zero, see blockParams.
+
+const (
+	queryAccount = "account"
+	querySupply  = "supply"
+	queryStorage = "storage"
+	queryCode    = "code"
+	queryReceipt = "receipt"
+)
+
+// queryPath answers the query if its path is one of the above
+func (app *PlutoApplication) queryPath(query abciTypes.RequestQuery) (abciTypes.ResponseQuery, bool) {
+	parts := strings.Split(strings.TrimPrefix(query.Path, "/"), "/")
+
+	var handler func(block *ethTypes.Block) abciTypes.ResponseQuery
+	switch {
+	case parts[0] == querySupply && len(parts) == 1:
+		handler = app.querySupply
+	case parts[0] == queryAccount && len(parts) == 2:
+		handler = func(block *ethTypes.Block) abciTypes.ResponseQuery {
+			return app.queryAccount(block, parts[1], query.Prove)
+		}
+	case parts[0] == queryStorage && len(parts) == 3:
+		handler = func(block *ethTypes.Block) abciTypes.ResponseQuery {
+			return app.queryStorage(block, parts[1], parts[2], query.Prove)
+		}
+	case parts[0] == queryCode && len(parts) == 2:
+		handler = func(block *ethTypes.Block) abciTypes.ResponseQuery {
+			return app.queryCode(block, parts[1])
+		}
+	case parts[0] == queryReceipt && len(parts) == 2:
+		handler = func(block *ethTypes.Block) abciTypes.ResponseQuery {
+			return app.queryReceipt(block, parts[1])
+		}
+	default:
+		return abciTypes.ResponseQuery{}, false
+	}
+
+	block, errRes := app.queryBlock(query.Height)
+	if errRes != nil {
+		return *errRes, true
+	}
+	res := handler(block)
+	res.Height = block.Number().Int64()
+	return res, true
+}
+
+// queryBlock returns the block whose state is queried at the given height,
+// the latest one for height zero. The ethereum block number is the
+// tendermint height. Historical state is only kept by archive nodes
+// (--gcmode=archive), other nodes answer with CodePruned.
+func (app *PlutoApplication) queryBlock(height int64) (*ethTypes.Block, *abciTypes.ResponseQuery) {
+	blockchain := app.backend.Ethereum().BlockChain()
+	current := blockchain.CurrentBlock()
+	if height == 0 {
+		return current, nil
+	}
+	if height < 0 || uint64(height) > current.NumberU64() {
+		res := queryError(errors.CodeUnknownRequest,
+			fmt.Errorf("height %d is not committed, latest is %d", height, current.NumberU64()))
+		return nil, &res
+	}
+
+	block := blockchain.GetBlockByNumber(uint64(height))
+	if block == nil || !blockchain.HasBlockAndState(block.Hash(), block.NumberU64()) {
+		res := abciTypes.ResponseQuery{
+			Code:   CodePruned,
+			Log:    fmt.Sprintf("state at height %d is pruned", height),
+			Height: height,
+		}
+		return nil, &res
+	}
+	return block, nil
+}
+
+// blockParams are the positions of the block parameter of the json-rpc
+// methods that read the state
+var blockParams = map[string]int{
+	"eth_getBalance":          1,
+	"eth_getTransactionCount": 1,
+	"eth_getCode":             1,
+	"eth_getStorageAt":        2,
+	"eth_call":                1,
+}
+
+// setBlockParam points a forwarded json-rpc request at the given height.
+// Requests without a block parameter can only be answered from the latest
+// state, so they fail for any other height.
+func setBlockParam(req *jsonRequest, height int64) error {
+	if height == 0 {
+		return nil
+	}
+	index, ok := blockParams[req.Method]
+	if !ok {
+		return fmt.Errorf("method %q can't be queried at a height", req.Method)
+	}
+	for len(req.Params) <= index {
+		req.Params = append(req.Params, nil)
+	}
+	req.Params[index] = hexutil.EncodeUint64(uint64(height))
+	return nil
+}
+
+// querySupply returns the RLP encoded total supply of ether. Chains without
+// a supply counter have no known supply.
+func (app *PlutoApplication) querySupply(block *ethTypes.Block) abciTypes.ResponseQuery {
+	statedb, err := state.New(block.Root(), app.stateDatabase())
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+	supply, err := ethereum.TotalSupply(statedb)
+	if err != nil {
+		return queryError(errors.CodeUnknownRequest, err)
+	}
+	value, err := rlp.EncodeToBytes(supply)
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+	return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Value: value}
+}
+
+// queryAccount returns the account leaf of the state trie
+func (app *PlutoApplication) queryAccount(block *ethTypes.Block, hexAddress string,
+	prove bool) abciTypes.ResponseQuery {
+
+	address, err := parseAddress(hexAddress)
+	if err != nil {
+		return queryError(errors.CodeInvalidAddress, err)
+	}
+	tr, err := app.stateDatabase().OpenTrie(block.Root())
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+	value, err := tr.TryGet(address[:])
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+	res := abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Key: address[:], Value: value}
+	if prove {
+		op, err := proveKey(tr, ProofOpAccount, address[:])
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+		res.Proof = &merkle.Proof{Ops: []merkle.ProofOp{op}}
+	}
+	return res
+}
+
+// queryStorage returns the value of a storage slot of an account
+func (app *PlutoApplication) queryStorage(block *ethTypes.Block, hexAddress, hexSlot string,
+	prove bool) abciTypes.ResponseQuery {
+
+	address, err := parseAddress(hexAddress)
+	if err != nil {
+		return queryError(errors.CodeInvalidAddress, err)
+	}
+	slot, err := parseHash(hexSlot)
+	if err != nil {
+		return queryError(errors.CodeUnknownRequest, err)
+	}
+
+	value := common.Hash{}
+	proof := new(merkle.Proof)
+	account, err := app.readAccount(block, address)
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+	if account != nil {
+		tr, err := app.stateDatabase().OpenStorageTrie(crypto.Keccak256Hash(address[:]), account.Root)
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+		enc, err := tr.TryGet(slot[:])
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+		if len(enc) > 0 {
+			_, content, _, err := rlp.Split(enc)
+			if err != nil {
+				return queryError(errors.CodeInternal, err)
+			}
+			value = common.BytesToHash(content)
+		}
+		if prove {
+			op, err := proveKey(tr, ProofOpStorage, slot[:])
+			if err != nil {
+				return queryError(errors.CodeInternal, err)
+			}
+			proof.Ops = append(proof.Ops, op)
+		}
+	}
+
+	res := abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Key: slot[:], Value: value[:]}
+	if prove {
+		tr, err := app.stateDatabase().OpenTrie(block.Root())
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+		op, err := proveKey(tr, ProofOpAccount, address[:])
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+		proof.Ops = append(proof.Ops, op)
+		res.Proof = proof
+	}
+	return res
+}
+
+// queryCode returns the code of an account
+func (app *PlutoApplication) queryCode(block *ethTypes.Block, hexAddress string) abciTypes.ResponseQuery {
+	address, err := parseAddress(hexAddress)
+	if err != nil {
+		return queryError(errors.CodeInvalidAddress, err)
+	}
+	account, err := app.readAccount(block, address)
+	if err != nil {
+		return queryError(errors.CodeInternal, err)
+	}
+
+	var code []byte
+	if account != nil && common.BytesToHash(account.CodeHash) != emptyCodeHash {
+		code, err = app.stateDatabase().ContractCode(crypto.Keccak256Hash(address[:]),
+			common.BytesToHash(account.CodeHash))
+		if err != nil {
+			return queryError(errors.CodeInternal, err)
+		}
+	}
+	return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Key: address[:], Value: code}
+}
+
+// queryReceipt returns the receipt summary of a tx included up to the block
+func (app *PlutoApplication) queryReceipt(block *ethTypes.Block, hexHash string) abciTypes.ResponseQuery {
+	hash, err := parseHash(hexHash)
+	if err != nil {
+		return queryError(errors.CodeUnknownRequest, err)
+	}
+
+	db := app.backend.Ethereum().ChainDb()
+	receipt, blockHash, number, _ := rawdb.ReadReceipt(db, hash)
+	if receipt == nil || number > block.NumberU64() ||
+		rawdb.ReadCanonicalHash(db, number) != blockHash {
+		return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Key: hash[:]}
+	}
+	return abciTypes.ResponseQuery{Code: abciTypes.CodeTypeOK, Key: hash[:], Value: encodeReceipt(receipt)}
+}
+
+//-------------------------------------------------------
+// Merkle proofs
+
+// Proof op types. The key of an op is an address or a storage slot, and its
+// data is the RLP list of the trie nodes on the path to the keccak256 hash of
+// the key, starting at the root. Ethereum tries are secure tries, they store
+// every value under the hash of its key. The nodes prove the presence of the
+// value, or its absence, with trie.VerifyProof(root, keccak256(key), nodes):
+// the root is the storage root of the account for ProofOpStorage, which
+// precedes the ProofOpAccount of the account, and the state root for
+// ProofOpAccount.
+const (
+	ProofOpAccount = "eth:account"
+	ProofOpStorage = "eth:storage"
+)
+
+// proofNodes collects the trie nodes of a proof in order
+type proofNodes [][]byte
+
+// Put implements ethdb.Putter
+func (n *proofNodes) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+// proveKey builds a proof op for the key of a secure trie. Prove doesn't hash
+// the key like the getters of the trie do.
+func proveKey(tr state.Trie, opType string, key []byte) (merkle.ProofOp, error) {
+	var nodes proofNodes
+	if err := tr.Prove(crypto.Keccak256(key), 0, &nodes); err != nil {
+		return merkle.ProofOp{}, err
+	}
+	data, err := rlp.EncodeToBytes([][]byte(nodes))
+	if err != nil {
+		return merkle.ProofOp{}, err
+	}
+	return merkle.ProofOp{Type: opType, Key: common.CopyBytes(key), Data: data}, nil
+}
+
+//-------------------------------------------------------
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// stateDatabase returns the database of the ethereum state tries
+func (app *PlutoApplication) stateDatabase() state.Database {
+	return app.backend.Ethereum().BlockChain().StateCache()
+}
+
+// readAccount decodes an account from the state trie, nil if it doesn't exist
+func (app *PlutoApplication) readAccount(block *ethTypes.Block, address common.Address) (*state.Account, error) {
+	tr, err := app.stateDatabase().OpenTrie(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	enc, err := tr.TryGet(address[:])
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(enc, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func queryError(code errors.CodeType, err error) abciTypes.ResponseQuery {
+	return abciTypes.ResponseQuery{Code: uint32(code), Log: err.Error()}
+}
+
+func parseAddress(s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	return common.HexToAddress(s), nil
+}
+
+func parseHash(s string) (common.Hash, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil || len(b) > common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid hash %q", s)
+	}
+	return common.BytesToHash(b), nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+
+	"github.com/zhuzeyu/pluto/ethereum"
+)
+
+// verifyProofOp checks a proof op against the root and returns the proven
+// value, which is empty if the key is absent
+func verifyProofOp(t *testing.T, root common.Hash, op merkle.ProofOp, opType string) []byte {
+	if op.Type != opType {
+		t.Fatalf("expected a %s proof op, got %s", opType, op.Type)
+	}
+	var nodes [][]byte
+	if err := rlp.DecodeBytes(op.Data, &nodes); err != nil {
+		t.Fatal(err)
+	}
+	db := ethdb.NewMemDatabase()
+	for _, node := range nodes {
+		if err := db.Put(crypto.Keccak256(node), node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	value, _, err := trie.VerifyProof(root, crypto.Keccak256(op.Key), db)
+	if err != nil {
+		t.Fatalf("invalid %s proof: %v", opType, err)
+	}
+	return value
+}
+
+func TestQueryProofs(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "pluto-query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir) // nolint: errcheck
+	writeTestGenesis(t, dataDir)
+
+	stack, app := newTestApp(t, dataDir)
+	defer stack.Stop() // nolint: errcheck
+	app.InitChain(abciTypes.RequestInitChain{})
+	commitBlock(t, app, 1)
+	block := app.backend.Ethereum().BlockChain().CurrentBlock()
+
+	// an account of the genesis
+	address := common.HexToAddress("0x7eff122b94897ea5b0e2a9abf47b86337fafebdc")
+	res := app.Query(abciTypes.RequestQuery{Path: fmt.Sprintf("/account/%x", address), Prove: true})
+	if res.IsErr() || res.Proof == nil || len(res.Proof.Ops) != 1 {
+		t.Fatalf("expected an account with a proof, got %v", res)
+	}
+	if value := verifyProofOp(t, block.Root(), res.Proof.Ops[0], ProofOpAccount); !bytes.Equal(value, res.Value) {
+		t.Errorf("expected the proof of %X, got %X", res.Value, value)
+	}
+
+	// a missing account is proven absent
+	res = app.Query(abciTypes.RequestQuery{Path: "/account/0x01", Prove: true})
+	if res.IsErr() || len(res.Value) != 0 || res.Proof == nil {
+		t.Fatalf("expected a missing account with a proof, got %v", res)
+	}
+	if value := verifyProofOp(t, block.Root(), res.Proof.Ops[0], ProofOpAccount); len(value) != 0 {
+		t.Errorf("expected the proof of absence, got %X", value)
+	}
+
+	// the supply counter is kept in the storage of the supply address
+	slot := crypto.Keccak256Hash([]byte("supply"))
+	res = app.Query(abciTypes.RequestQuery{
+		Path:  fmt.Sprintf("/storage/%x/%x", ethereum.SupplyAddress, slot),
+		Prove: true,
+	})
+	if res.IsErr() || res.Proof == nil || len(res.Proof.Ops) != 2 {
+		t.Fatalf("expected a storage slot with a proof, got %v", res)
+	}
+	enc := verifyProofOp(t, block.Root(), res.Proof.Ops[1], ProofOpAccount)
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(enc, account); err != nil {
+		t.Fatal(err)
+	}
+	enc = verifyProofOp(t, account.Root, res.Proof.Ops[0], ProofOpStorage)
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := common.BytesToHash(content); !bytes.Equal(value[:], res.Value) {
+		t.Errorf("expected the proof of %X, got %X", res.Value, value)
+	}
+}
+
+func TestSetBlockParam(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    jsonRequest
+		height int64
+		params []interface{}
+		err    bool
+	}{
+		{"latest", jsonRequest{Method: "eth_blockNumber"}, 0, nil, false},
+		{"latest with block parameter", jsonRequest{Method: "eth_getBalance",
+			Params: []interface{}{"0x01", "latest"}}, 0, []interface{}{"0x01", "latest"}, false},
+		{"block parameter replaced", jsonRequest{Method: "eth_getBalance",
+			Params: []interface{}{"0x01", "latest"}}, 5, []interface{}{"0x01", "0x5"}, false},
+		{"block parameter added", jsonRequest{Method: "eth_getStorageAt",
+			Params: []interface{}{"0x01"}}, 16, []interface{}{"0x01", nil, "0x10"}, false},
+		{"no block parameter", jsonRequest{Method: "eth_blockNumber"}, 5, nil, true},
+	}
+	for _, test := range tests {
+		req := test.req
+		if err := setBlockParam(&req, test.height); (err != nil) != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if !test.err && fmt.Sprint(req.Params) != fmt.Sprint(test.params) {
+			t.Errorf("%s: expected params %v, got %v", test.name, test.params, req.Params)
+		}
+	}
+}
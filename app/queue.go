@@ -0,0 +1,152 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+//----------------------------------------------------------------------
+// Txs with a nonce ahead of the sender's are held back by CheckTx instead of
+// being rejected, like in the queue of the geth tx pool. As soon as the gap
+// is filled they are handed to the tendermint mempool again. The queue is
+// bounded per sender and in total, and txs that wait too long are dropped.
+
+// maxQueuedPerAccount bounds the number of future txs held for a sender and
+// how far ahead of the sender's nonce they can be
+const maxQueuedPerAccount = 64
+
+// maxQueued bounds the number of future txs held for all senders, like the
+// global queue of the geth tx pool
+const maxQueued = 1024
+
+// queueLifetime is how long a future tx is held before it is dropped, like
+// the lifetime of the geth tx pool
+const queueLifetime = 3 * time.Hour
+
+// queuedTx is a future tx and the time it was queued
+type queuedTx struct {
+	tx   *ethTypes.Transaction
+	time time.Time
+}
+
+// txQueue holds future txs by sender and nonce
+type txQueue struct {
+	mtx   sync.Mutex
+	txs   map[common.Address]map[uint64]queuedTx
+	count int
+
+	now func() time.Time
+}
+
+func newTxQueue() *txQueue {
+	return &txQueue{
+		txs: make(map[common.Address]map[uint64]queuedTx),
+		now: time.Now,
+	}
+}
+
+// add queues a tx ahead of the sender's current nonce. A queued tx is only
+// replaced by one with a higher gas price. Returns false if the tx is too
+// far ahead or the queue of the sender or all queues are full, even after
+// dropping the expired txs.
+func (q *txQueue) add(from common.Address, nonce uint64, tx *ethTypes.Transaction) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if tx.Nonce() >= nonce+maxQueuedPerAccount {
+		return false
+	}
+	queued := q.txs[from]
+	if old, ok := queued[tx.Nonce()]; ok {
+		if old.tx.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+			return false
+		}
+		queued[tx.Nonce()] = queuedTx{tx, q.now()}
+		return true
+	}
+	if len(queued) >= maxQueuedPerAccount {
+		return false
+	}
+	if q.count >= maxQueued {
+		if q.expire(); q.count >= maxQueued {
+			return false
+		}
+	}
+
+	if queued = q.txs[from]; queued == nil {
+		queued = make(map[uint64]queuedTx)
+		q.txs[from] = queued
+	}
+	queued[tx.Nonce()] = queuedTx{tx, q.now()}
+	q.count++
+	return true
+}
+
+// pop removes and returns the queued tx of the sender with the given nonce
+func (q *txQueue) pop(from common.Address, nonce uint64) *ethTypes.Transaction {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	queued, ok := q.txs[from][nonce]
+	if !ok {
+		return nil
+	}
+	q.remove(from, nonce)
+	return queued.tx
+}
+
+// ready drops the expired txs and those made stale by the given state, and
+// removes and returns those that are next in line for their sender
+func (q *txQueue) ready(statedb *state.StateDB) []*ethTypes.Transaction {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.expire()
+	var txs []*ethTypes.Transaction
+	for from, queued := range q.txs {
+		nonce := statedb.GetNonce(from)
+		for n := range queued {
+			if n < nonce {
+				q.remove(from, n)
+			}
+		}
+		if next, ok := queued[nonce]; ok {
+			txs = append(txs, next.tx)
+			q.remove(from, nonce)
+		}
+	}
+	return txs
+}
+
+// expire drops the txs queued longer than queueLifetime
+func (q *txQueue) expire() {
+	deadline := q.now().Add(-queueLifetime)
+	for from, queued := range q.txs {
+		for n, queuedTx := range queued {
+			if queuedTx.time.Before(deadline) {
+				q.remove(from, n)
+			}
+		}
+	}
+}
+
+// remove drops a queued tx
+func (q *txQueue) remove(from common.Address, nonce uint64) {
+	queued := q.txs[from]
+	delete(queued, nonce)
+	q.count--
+	if len(queued) == 0 {
+		delete(q.txs, from)
+	}
+}
+
+// promote hands a queued tx back to the mempool. CheckTx and Commit run
+// with the mempool locked, so it must not wait for the result.
+func (app *PlutoApplication) promote(tx *ethTypes.Transaction) {
+	app.logger.Debug("Promoting queued tx", "hash", tx.Hash()) // nolint: errcheck
+	app.backend.ResubmitTx(tx)
+}
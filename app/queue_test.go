@@ -0,0 +1,120 @@
+package app
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func newTestTx(nonce uint64, gasPrice int64) *ethTypes.Transaction {
+	return ethTypes.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1),
+		21000, big.NewInt(gasPrice), nil)
+}
+
+func TestTxQueueAdd(t *testing.T) {
+	q := newTxQueue()
+	from := common.HexToAddress("0xf0")
+
+	if !q.add(from, 0, newTestTx(2, 1)) {
+		t.Fatal("expected the tx to be queued")
+	}
+	if q.add(from, 0, newTestTx(2, 1)) {
+		t.Error("expected a tx with the same gas price not to replace the queued one")
+	}
+	if !q.add(from, 0, newTestTx(2, 2)) {
+		t.Error("expected a tx with a higher gas price to replace the queued one")
+	}
+	if q.add(from, 0, newTestTx(maxQueuedPerAccount, 1)) {
+		t.Error("expected a tx too far ahead to be rejected")
+	}
+
+	tx := q.pop(from, 2)
+	if tx == nil || tx.GasPrice().Int64() != 2 {
+		t.Fatalf("expected the replacement tx, got %v", tx)
+	}
+	if q.pop(from, 2) != nil {
+		t.Error("expected the tx to be removed")
+	}
+}
+
+func TestTxQueueReady(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := newTxQueue()
+	from := common.HexToAddress("0xf0")
+	for _, nonce := range []uint64{1, 2, 4} {
+		q.add(from, 0, newTestTx(nonce, 1))
+	}
+
+	// nonce 0 is missing
+	if txs := q.ready(statedb); len(txs) != 0 {
+		t.Fatalf("expected no ready txs, got %d", len(txs))
+	}
+
+	// nonce 1 is stale, 2 is next
+	statedb.SetNonce(from, 2)
+	txs := q.ready(statedb)
+	if len(txs) != 1 || txs[0].Nonce() != 2 {
+		t.Fatalf("expected the tx with nonce 2, got %v", txs)
+	}
+	if q.pop(from, 1) != nil {
+		t.Error("expected the stale tx to be dropped")
+	}
+	if q.pop(from, 4) == nil {
+		t.Error("expected the tx with nonce 4 to stay queued")
+	}
+}
+
+func TestTxQueueLimit(t *testing.T) {
+	q := newTxQueue()
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	for i := 0; i < maxQueued; i++ {
+		from := common.BigToAddress(big.NewInt(int64(i/maxQueuedPerAccount + 1)))
+		if !q.add(from, 0, newTestTx(uint64(i%maxQueuedPerAccount), 1)) {
+			t.Fatalf("expected tx %d to be queued", i)
+		}
+	}
+	from := common.HexToAddress("0xf0")
+	if q.add(from, 0, newTestTx(1, 1)) {
+		t.Fatal("expected the full queue to reject the tx")
+	}
+
+	// the expired txs make room
+	now = now.Add(queueLifetime + time.Second)
+	if !q.add(from, 0, newTestTx(1, 1)) {
+		t.Fatal("expected the tx to be queued after the others expired")
+	}
+	if q.count != 1 {
+		t.Errorf("expected 1 queued tx, got %d", q.count)
+	}
+}
+
+func TestTxQueueExpiry(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := newTxQueue()
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	from := common.HexToAddress("0xf0")
+	q.add(from, 0, newTestTx(1, 1))
+	now = now.Add(queueLifetime + time.Second)
+	statedb.SetNonce(from, 1)
+	if txs := q.ready(statedb); len(txs) != 0 {
+		t.Errorf("expected the expired tx to be dropped, got %v", txs)
+	}
+	if q.count != 0 {
+		t.Errorf("expected an empty queue, got %d", q.count)
+	}
+}
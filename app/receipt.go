@@ -0,0 +1,43 @@
+package app
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ReceiptSummary is the part of a receipt that DeliverTx returns RLP encoded
+// in its Data, so the result of a tx can be read without asking the ethereum
+// rpc. The logs are encoded with their address, topics and data.
+// #unstable
+type ReceiptSummary struct {
+	Status          uint64
+	GasUsed         uint64
+	ContractAddress common.Address // zero unless the tx created a contract
+	Logs            []*ethTypes.Log
+}
+
+// newReceiptSummary summarises a receipt, which may be nil if the tx didn't
+// make it into the block
+func newReceiptSummary(receipt *ethTypes.Receipt) ReceiptSummary {
+	if receipt == nil {
+		return ReceiptSummary{Logs: []*ethTypes.Log{}}
+	}
+	logs := receipt.Logs
+	if logs == nil {
+		logs = []*ethTypes.Log{}
+	}
+	return ReceiptSummary{
+		Status:          receipt.Status,
+		GasUsed:         receipt.GasUsed,
+		ContractAddress: receipt.ContractAddress,
+		Logs:            logs,
+	}
+}
+
+// encodeReceipt RLP encodes the summary of a receipt
+func encodeReceipt(receipt *ethTypes.Receipt) []byte {
+	data, _ := rlp.EncodeToBytes(newReceiptSummary(receipt)) // nolint: errcheck
+	return data
+}
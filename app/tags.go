@@ -0,0 +1,53 @@
+package app
+
+import (
+	"strings"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+//----------------------------------------------------------------------
+// DeliverTx tags the txs so they can be found with the tx_search of
+// tendermint and subscribed to. Addresses and hashes are lower case 0x
+// prefixed hex. There is a log tag pair for every log of the tx.
+
+// Tag keys of DeliverTx
+const (
+	TagHash       = "eth.hash"
+	TagFrom       = "eth.from"
+	TagTo         = "eth.to"
+	TagContract   = "eth.contract"
+	TagLogAddress = "eth.log.address"
+	TagLogTopic0  = "eth.log.topic0"
+)
+
+// txTags returns the tags of a tx that made it into the block
+func txTags(tx *ethTypes.Transaction, receipt *ethTypes.Receipt) []cmn.KVPair {
+	tags := []cmn.KVPair{tag(TagHash, tx.Hash().Hex())}
+
+	var signer ethTypes.Signer = ethTypes.FrontierSigner{}
+	if tx.Protected() {
+		signer = ethTypes.NewEIP155Signer(tx.ChainId())
+	}
+	if from, err := ethTypes.Sender(signer, tx); err == nil {
+		tags = append(tags, tag(TagFrom, from.Hex()))
+	}
+	if to := tx.To(); to != nil {
+		tags = append(tags, tag(TagTo, to.Hex()))
+	} else {
+		tags = append(tags, tag(TagContract, receipt.ContractAddress.Hex()))
+	}
+
+	for _, log := range receipt.Logs {
+		tags = append(tags, tag(TagLogAddress, log.Address.Hex()))
+		if len(log.Topics) > 0 {
+			tags = append(tags, tag(TagLogTopic0, log.Topics[0].Hex()))
+		}
+	}
+	return tags
+}
+
+func tag(key, value string) cmn.KVPair {
+	return cmn.KVPair{Key: []byte(key), Value: []byte(strings.ToLower(value))}
+}
@@ -3,12 +3,15 @@ package app
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
 
 	abciTypes "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	emtTypes "github.com/zhuzeyu/pluto/types"
 )
 
 // format of query data
@@ -18,46 +21,183 @@ type jsonRequest struct {
 	Params []interface{}   `json:"params,omitempty"`
 }
 
-// rlp decode an etherum transaction
-func decodeTx(txBytes []byte) (*types.Transaction, error) {
-	tx := new(types.Transaction)
-	rlpStream := rlp.NewStream(bytes.NewBuffer(txBytes), 0)
-	if err := tx.DecodeRLP(rlpStream); err != nil {
-		return nil, err
-	}
-	return tx, nil
-}
-
 //-------------------------------------------------------
 // convenience methods for validators
 
 // Receiver returns the receiving address based on the selected strategy
 // #unstable
 func (app *PlutoApplication) Receiver() common.Address {
-	if app.strategy != nil {
+	if app.strategy != nil && app.strategy.MinerRewardStrategy != nil {
 		return app.strategy.Receiver()
 	}
 	return common.Address{}
 }
 
-// SetValidators sets new validators on the strategy
+// initValidators sets the genesis validators on the strategy
+func (app *PlutoApplication) initValidators(validators []abciTypes.ValidatorUpdate) {
+	if app.strategy != nil {
+		app.strategy.InitChain(validators)
+	}
+}
+
+// RestoreValidators sets the current validator set on the strategy after a
+// restart
+// #unstable
+func (app *PlutoApplication) RestoreValidators(validators []abciTypes.ValidatorUpdate) {
+	if app.strategy != nil {
+		app.strategy.RestoreValidators(validators)
+	}
+}
+
+// LoadValidators restores the validator set of the strategy stored at the
+// current height of the ethereum chain. It returns false if there is none,
+// e.g. for blocks committed by older versions.
 // #unstable
-func (app *PlutoApplication) SetValidators(validators []abciTypes.ValidatorUpdate) {
+func (app *PlutoApplication) LoadValidators() (bool, error) {
+	if app.strategy == nil {
+		return true, nil
+	}
+	number := app.backend.Ethereum().BlockChain().CurrentBlock().NumberU64()
+	validators, ok, err := app.backend.Validators(number)
+	if err != nil || !ok {
+		return false, err
+	}
+	app.strategy.RestoreValidators(validators)
+	return true, nil
+}
+
+// storeValidators stores the validator set of the strategy after the given
+// block, so that LoadValidators finds it after a restart
+func (app *PlutoApplication) storeValidators(number uint64) error {
+	if app.strategy == nil {
+		return nil
+	}
+	return app.backend.WriteValidators(number, app.strategy.Validators())
+}
+
+// UpdateValidators applies validator updates to the strategy
+// #unstable
+func (app *PlutoApplication) UpdateValidators(updates []abciTypes.ValidatorUpdate) {
 	if app.strategy != nil {
-		app.strategy.SetValidators(validators)
+		app.strategy.UpdateValidators(updates)
 	}
 }
 
 // GetUpdatedValidators returns an updated validator set from the strategy
 // #unstable
 func (app *PlutoApplication) GetUpdatedValidators() abciTypes.ResponseEndBlock {
+	if app.strategy != nil {
+		return abciTypes.ResponseEndBlock{ValidatorUpdates: app.strategy.ValidatorUpdates()}
+	}
 	return abciTypes.ResponseEndBlock{}
 }
 
-// CollectTx invokes CollectTx on the strategy
+// contractValidatorUpdates diffs the validator set held by the validator
+// contract against the current one. If the contract can't be read, the
+// validator set stays as it is.
 // #unstable
-func (app *PlutoApplication) CollectTx(tx *types.Transaction) {
+func (app *PlutoApplication) contractValidatorUpdates() []abciTypes.ValidatorUpdate {
+	if app.strategy == nil {
+		return nil
+	}
+	validators, err := app.backend.ContractValidators()
+	if err != nil {
+		// nolint: errcheck
+		app.logger.Error("Error reading the validator contract", "err", err)
+		return nil
+	}
+	return app.strategy.Diff(validators)
+}
+
+// deliverValidatorsTx hands a delivered tx and its receipt to the strategy
+func (app *PlutoApplication) deliverValidatorsTx(tx *types.Transaction, receipt *types.Receipt) {
 	if app.strategy != nil {
-		app.strategy.CollectTx(tx)
+		app.strategy.DeliverTx(tx, receipt)
+	}
+}
+
+// endValidatorsBlock applies the validator updates of the strategy
+func (app *PlutoApplication) endValidatorsBlock(height int64) {
+	if app.strategy != nil {
+		app.strategy.EndBlock(height)
+	}
+}
+
+// updateMaxGas lets the strategy change the block gas limit. The new limit
+// applies from the next block on and is reported to tendermint, which then
+// keeps the gas wanted by the txs of a block within it.
+// #unstable
+func (app *PlutoApplication) updateMaxGas(height int64) *abciTypes.ConsensusParams {
+	// the update replaces the whole block size, which isn't known without
+	// the consensus params of InitChain
+	current := app.backend.BlockSize()
+	if app.strategy == nil || current.MaxBytes <= 0 {
+		return nil
+	}
+	maxGas, ok := app.strategy.MaxGas(height, current.MaxGas)
+	if !ok {
+		return nil
+	}
+	blockSize := app.backend.UpdateMaxGas(maxGas)
+	app.logger.Info("Changed block gas limit", "maxGas", maxGas) // nolint: errcheck
+	return &abciTypes.ConsensusParams{
+		BlockSize: &abciTypes.BlockSize{
+			MaxBytes: blockSize.MaxBytes,
+			MaxGas:   blockSize.MaxGas,
+		},
+	}
+}
+
+// slashDoubleSign slashes and jails the validator named in the evidence.
+// Validators without stake can't be slashed and are only removed from the
+// validator set. The returned tags make the slashing visible to tendermint
+// subscribers, and a slashing of stake is also logged from the staking
+// address, see staking.SlashedTopic and pluto_getStakingLogs.
+// #unstable
+func (app *PlutoApplication) slashDoubleSign(evidence abciTypes.Evidence) []cmn.KVPair {
+	address := evidence.Validator.Address
+	tags := []cmn.KVPair{
+		{Key: []byte("slash.validator"), Value: []byte(fmt.Sprintf("%X", address))},
+		{Key: []byte("slash.height"), Value: []byte(fmt.Sprintf("%d", evidence.Height))},
+	}
+
+	if slash := app.backend.SlashDoubleSign(address); slash != nil {
+		// nolint: errcheck
+		app.logger.Info("Slashed validator for double signing", "validator",
+			fmt.Sprintf("%X", address), "amount", slash.Amount)
+		return append(tags, cmn.KVPair{Key: []byte("slash.amount"), Value: []byte(slash.Amount.String())})
+	}
+
+	if app.strategy == nil {
+		return tags
+	}
+	for _, validator := range app.strategy.Validators() {
+		validatorAddress, err := emtTypes.ValidatorAddress(validator)
+		if err != nil || !bytes.Equal(validatorAddress, address) {
+			continue
+		}
+		// nolint: errcheck
+		app.logger.Info("Removed validator for double signing", "validator",
+			fmt.Sprintf("%X", address))
+		app.strategy.UpdateValidators([]abciTypes.ValidatorUpdate{{PubKey: validator.PubKey, Power: 0}})
+		break
+	}
+	return tags
+}
+
+// handleVotes tracks which validators signed the last block. The returned
+// tags name the validators jailed for downtime.
+// #unstable
+func (app *PlutoApplication) handleVotes(votes []abciTypes.VoteInfo) []cmn.KVPair {
+	var tags []cmn.KVPair
+	for _, jail := range app.backend.HandleVotes(votes) {
+		address := jail.Address()
+		// nolint: errcheck
+		app.logger.Info("Jailed validator for downtime", "validator",
+			fmt.Sprintf("%X", address), "until", jail.Until)
+		tags = append(tags,
+			cmn.KVPair{Key: []byte("jail.validator"), Value: []byte(fmt.Sprintf("%X", address))},
+			cmn.KVPair{Key: []byte("jail.until"), Value: []byte(fmt.Sprintf("%d", jail.Until))})
 	}
+	return tags
 }
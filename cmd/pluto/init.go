@@ -13,18 +13,17 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/tendermint/ethermint/cmd/utils"
+	"github.com/zhuzeyu/pluto/cmd/utils"
 
-	emtUtils "github.com/tendermint/ethermint/cmd/utils"
+	emtUtils "github.com/zhuzeyu/pluto/cmd/utils"
+	"github.com/zhuzeyu/pluto/ethereum"
+	plutoUtils "github.com/zhuzeyu/pluto/utils"
 )
 
 // nolint: gocyclo
 func initCmd(ctx *cli.Context) error {
 	genesisPath := ctx.Args().First()
-	genesis, err := emtUtils.ParseGenesisOrDefault(genesisPath)
-	if err != nil {
-		ethUtils.Fatalf("genesisJSON err: %v", err)
-	}
+	genesis, params := plutoUtils.ReadGenesis(genesisPath)
 
 	ethermintDataDir := emtUtils.MakeDataDir(ctx)
 
@@ -35,7 +34,7 @@ func initCmd(ctx *cli.Context) error {
 	if canInvokeTendermintInit {
 		tendermintHome := filepath.Join(ethermintDataDir, "tendermint")
 		tendermintArgs := []string{"init", "--home", tendermintHome}
-		_, err = invokeTendermint(tendermintArgs...)
+		_, err := invokeTendermint(tendermintArgs...)
 		if err != nil {
 			ethUtils.Fatalf("tendermint init error: %v", err)
 		}
@@ -48,6 +47,10 @@ func initCmd(ctx *cli.Context) error {
 		ethUtils.Fatalf("could not open database: %v", err)
 	}
 
+	if err := ethereum.SeedSupply(genesis); err != nil {
+		ethUtils.Fatalf("failed to seed the supply counter: %v", err)
+	}
+
 	_, hash, err := core.SetupGenesisBlock(chainDb, genesis)
 	if err != nil {
 		ethUtils.Fatalf("failed to write genesis block: %v", err)
@@ -55,6 +58,10 @@ func initCmd(ctx *cli.Context) error {
 
 	log.Info("successfully wrote genesis block and/or chain rule set", "hash", hash)
 
+	if err := ethereum.WriteParams(chainDb, params); err != nil {
+		ethUtils.Fatalf("failed to write pluto params: %v", err)
+	}
+
 	// As per https://github.com/tendermint/ethermint/issues/244#issuecomment-322024199
 	// Let's implicitly add in the respective keystore files
 	// to avoid manually doing this step:
@@ -66,6 +66,8 @@ var (
 		utils.VerbosityFlag,
 		utils.ConfigFileFlag,
 		utils.WithTendermintFlag,
+		utils.QueryAllowFlag,
+		utils.QueryDenyFlag,
 	}
 
 	// flags that configure the ABCI app
@@ -28,7 +28,7 @@ import (
 
 func plutoCmd(ctx *cli.Context) error {
 	// Step 1: Setup the go-ethereum node and start it
-	node := emtUtils.MakeFullNode(ctx)
+	node, plutoConfig := emtUtils.MakeFullNode(ctx)
 	startNode(ctx, node)
 
 	// Setup the ABCI server and start it
@@ -47,12 +47,20 @@ func plutoCmd(ctx *cli.Context) error {
 		ethUtils.Fatalf("Failed to attach to the inproc geth: %v", err)
 	}
 
+	strategy, err := emtUtils.MakeStrategy(backend)
+	if err != nil {
+		ethUtils.Fatalf("Failed to create the strategy: %v", err)
+	}
+
 	// Create the ABCI app
-	ethApp, err := abciApp.NewPlutoApplication(backend, rpcClient, nil)
+	ethApp, err := abciApp.NewPlutoApplication(backend, rpcClient, strategy)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	ethApp.SetMinGasPrice(plutoConfig.MinGasPrice)
+	ethApp.SetQueryMethods(plutoConfig.QueryAllow, plutoConfig.QueryDeny)
+	ethApp.SetVerbosityHandler(emtUtils.SetVerbosity)
 	ethApp.SetLogger(tmlog.NewTMLogger(tmlog.NewSyncWriter(os.Stdout)).With("module", "ethermint"))
 
 	// Step 2: If we can invoke `tendermint node`, let's do so
@@ -61,6 +69,9 @@ func plutoCmd(ctx *cli.Context) error {
 	canInvokeTendermintNode := canInvokeTendermint(ctx)
 	if canInvokeTendermintNode {
 		tmConfig := loadTMConfig(ctx)
+		if err := recoverState(tmConfig, backend, ethApp); err != nil {
+			ethUtils.Fatalf("Failed to recover the state: %v", err)
+		}
 		clientCreator := proxy.NewLocalClientCreator(ethApp)
 		tmLogger := tmlog.NewTMLogger(tmlog.NewSyncWriter(os.Stdout)).With("module", "tendermint")
 
@@ -96,6 +107,12 @@ func plutoCmd(ctx *cli.Context) error {
 		return nil
 
 	} else {
+		// The stores of a tendermint running on the same data dir can be
+		// used, unless it already has them open
+		if err := recoverState(loadTMConfig(ctx), backend, ethApp); err != nil {
+			log.Warn("Failed to recover the state from tendermint", "err", err)
+		}
+
 		// Start the app on the ABCI server
 		srv, err := server.NewServer(addr, abci, ethApp)
 		if err != nil {
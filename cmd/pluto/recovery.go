@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	tmcfg "github.com/tendermint/tendermint/config"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	tmNode "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	abciApp "github.com/zhuzeyu/pluto/app"
+	"github.com/zhuzeyu/pluto/ethereum"
+)
+
+// recoverState reconciles the ethereum chain with the tendermint stores
+// before the tendermint node is started.
+//
+// Tendermint saves a block before it is executed, so after a crash the
+// ethereum chain can only be ahead of the block store if the tendermint
+// data was lost, and then it is rolled back. If it is behind, the ABCI
+// handshake replays the missing blocks through DeliverTx once the node is
+// started.
+//
+// Since InitChain isn't called again, the validator set of the strategy is
+// restored from the one stored with the ethereum block, or else from the
+// tendermint state at the height of the ethereum chain.
+//
+// Without tendermint stores, e.g. when tendermint runs elsewhere and the
+// app is connected over a socket, there is nothing to reconcile.
+func recoverState(tmConfig *tmcfg.Config, backend *ethereum.Backend,
+	ethApp *abciApp.PlutoApplication) error {
+
+	if !cmn.FileExists(filepath.Join(tmConfig.DBDir(), "blockstore.db")) {
+		log.Info("No tendermint stores to recover the state from", "dir", tmConfig.DBDir())
+		return nil
+	}
+
+	blockStoreDB, err := tmNode.DefaultDBProvider(&tmNode.DBContext{ID: "blockstore", Config: tmConfig})
+	if err != nil {
+		return err
+	}
+	storeHeight := uint64(bc.NewBlockStore(blockStoreDB).Height())
+	blockStoreDB.Close()
+
+	blockchain := backend.Ethereum().BlockChain()
+	ethHeight := blockchain.CurrentBlock().NumberU64()
+	switch {
+	case ethHeight > storeHeight:
+		log.Warn("Ethereum chain is ahead of tendermint, rolling back",
+			"ethereum", ethHeight, "tendermint", storeHeight)
+		if err := backend.Rollback(storeHeight, ethApp.Receiver()); err != nil {
+			return err
+		}
+		// geth rewinds further if the state of the block is missing
+		ethHeight = blockchain.CurrentBlock().NumberU64()
+		if ethHeight != storeHeight {
+			log.Warn("Ethereum chain rolled back below tendermint, blocks will be replayed",
+				"ethereum", ethHeight, "tendermint", storeHeight)
+		}
+	case ethHeight < storeHeight:
+		log.Warn("Ethereum chain is behind tendermint, blocks will be replayed",
+			"ethereum", ethHeight, "tendermint", storeHeight)
+	}
+
+	if ok, err := ethApp.LoadValidators(); err != nil || ok || ethHeight == 0 {
+		return err
+	}
+
+	stateDB, err := tmNode.DefaultDBProvider(&tmNode.DBContext{ID: "state", Config: tmConfig})
+	if err != nil {
+		return err
+	}
+	defer stateDB.Close()
+
+	// The updates of EndBlock at height h apply from height h+2
+	validators, err := sm.LoadValidators(stateDB, int64(ethHeight)+2)
+	if err != nil {
+		log.Warn("Failed to restore the validator set", "height", ethHeight, "err", err)
+		return nil
+	}
+	ethApp.RestoreValidators(tmTypes.TM2PB.ValidatorUpdates(validators))
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	bc "github.com/tendermint/tendermint/blockchain"
+	tmcfg "github.com/tendermint/tendermint/config"
+	tmLog "github.com/tendermint/tendermint/libs/log"
+	tmNode "github.com/tendermint/tendermint/node"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	abciApp "github.com/zhuzeyu/pluto/app"
+	"github.com/zhuzeyu/pluto/ethereum"
+	emtTypes "github.com/zhuzeyu/pluto/types"
+	plutoUtils "github.com/zhuzeyu/pluto/utils"
+)
+
+const testGenesis = `{
+	"config": {
+		"chainId": 15,
+		"homesteadBlock": 0,
+		"eip155Block": 0,
+		"eip158Block": 0
+	},
+	"difficulty": "0x40",
+	"gasLimit": "0x8000000",
+	"alloc": {}
+}`
+
+// testNode is an ethereum node with the app on top of it
+type testNode struct {
+	stack    *ethereum.Node
+	backend  *ethereum.Backend
+	app      *abciApp.PlutoApplication
+	strategy *emtTypes.Strategy
+}
+
+// initTestChain does what `pluto init` does with a small genesis file
+func initTestChain(t *testing.T, dataDir string) {
+	genesisPath := filepath.Join(dataDir, "genesis.json")
+	if err := ioutil.WriteFile(genesisPath, []byte(testGenesis), 0600); err != nil {
+		t.Fatal(err)
+	}
+	chainDb, err := ethdb.NewLDBDatabase(filepath.Join(dataDir, "ethermint", "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chainDb.Close()
+
+	// a block reward makes every block change the state root
+	genesis, plutoParams := plutoUtils.ReadGenesis(genesisPath)
+	plutoParams.BlockReward = (*math.HexOrDecimal256)(big.NewInt(params.Ether))
+	if err := ethereum.SeedSupply(genesis); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := core.SetupGenesisBlock(chainDb, genesis); err != nil {
+		t.Fatal(err)
+	}
+	if err := ethereum.WriteParams(chainDb, plutoParams); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startTestNode starts an archive node without networking on the data dir, so
+// the state of every block is on disk and can be rolled back to
+func startTestNode(t *testing.T, dataDir string) *testNode {
+	stack, err := ethereum.New(&node.Config{
+		Name:    "ethermint",
+		DataDir: dataDir,
+		NoUSB:   true,
+		P2P:     p2p.Config{NoDiscovery: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethConfig := eth.DefaultConfig
+	ethConfig.Ethash.PowMode = ethash.ModeFullFake
+	ethConfig.NoPruning = true
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return ethereum.NewBackend(ctx, &ethConfig, nil)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	n := &testNode{stack: stack, strategy: new(emtTypes.Strategy)}
+	if err := stack.Service(&n.backend); err != nil {
+		t.Fatal(err)
+	}
+	if n.app, err = abciApp.NewPlutoApplication(n.backend, nil, n.strategy); err != nil {
+		t.Fatal(err)
+	}
+	n.app.SetLogger(tmLog.NewNopLogger())
+	return n
+}
+
+// commitBlock runs an empty block through the app
+func (n *testNode) commitBlock(t *testing.T, height int64) {
+	n.app.BeginBlock(abciTypes.RequestBeginBlock{
+		Hash: crypto.Keccak256([]byte{byte(height)}),
+		Header: abciTypes.Header{
+			Height: height,
+			Time:   time.Unix(1500000000+height, 0),
+		},
+	})
+	n.app.EndBlock(abciTypes.RequestEndBlock{Height: height})
+	if len(n.app.Commit().Data) == 0 {
+		t.Fatalf("block %d not committed", height)
+	}
+}
+
+func testValidator(power int64) abciTypes.ValidatorUpdate {
+	return abciTypes.ValidatorUpdate{
+		PubKey: abciTypes.PubKey{Type: tmTypes.ABCIPubKeyTypeEd25519, Data: bytes.Repeat([]byte{1}, 32)},
+		Power:  power,
+	}
+}
+
+// writeBlockStore makes the tendermint block store report the given height
+func writeBlockStore(t *testing.T, tmConfig *tmcfg.Config, height int64) {
+	if err := os.MkdirAll(tmConfig.DBDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	db, err := tmNode.DefaultDBProvider(&tmNode.DBContext{ID: "blockstore", Config: tmConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.BlockStoreStateJSON{Height: height}.Save(db)
+	db.Close()
+}
+
+func TestRecoverState(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocks     int64 // committed to the ethereum chain
+		storeBlock int64 // height of the tendermint block store, -1 for none
+		height     uint64
+		power      int64 // of the restored validator set
+	}{
+		{"without tendermint stores", 3, -1, 3, 20},
+		{"in sync", 3, 3, 3, 20},
+		{"ethereum ahead", 3, 1, 1, 10},
+		{"ethereum behind", 2, 3, 2, 20},
+	}
+	for _, test := range tests {
+		dataDir, err := ioutil.TempDir("", "pluto-recovery")
+		if err != nil {
+			t.Fatal(err)
+		}
+		initTestChain(t, dataDir)
+
+		// the validator gains power with block 2
+		n := startTestNode(t, dataDir)
+		n.app.InitChain(abciTypes.RequestInitChain{Validators: []abciTypes.ValidatorUpdate{testValidator(10)}})
+		for height := int64(1); height <= test.blocks; height++ {
+			if height == 2 {
+				n.app.UpdateValidators([]abciTypes.ValidatorUpdate{testValidator(20)})
+			}
+			n.commitBlock(t, height)
+		}
+		if err := n.stack.Stop(); err != nil {
+			t.Fatal(err)
+		}
+
+		tmConfig := tmcfg.DefaultConfig()
+		tmConfig.SetRoot(filepath.Join(dataDir, "tendermint"))
+		if test.storeBlock >= 0 {
+			writeBlockStore(t, tmConfig, test.storeBlock)
+		}
+
+		n = startTestNode(t, dataDir)
+		if err := recoverState(tmConfig, n.backend, n.app); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if height := n.backend.Ethereum().BlockChain().CurrentBlock().NumberU64(); height != test.height {
+			t.Errorf("%s: expected height %d, got %d", test.name, test.height, height)
+		}
+		if validators := n.strategy.Validators(); len(validators) != 1 || validators[0].Power != test.power {
+			t.Errorf("%s: expected power %d, got %v", test.name, test.power, validators)
+		}
+		if info := n.app.Info(abciTypes.RequestInfo{}); uint64(info.LastBlockHeight) != test.height {
+			t.Errorf("%s: expected Info to report height %d, got %d", test.name, test.height, info.LastBlockHeight)
+		}
+
+		n.stack.Stop()        // nolint: errcheck
+		os.RemoveAll(dataDir) // nolint: errcheck
+	}
+}
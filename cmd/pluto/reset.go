@@ -3,7 +3,7 @@ package main
 import (
 	"gopkg.in/urfave/cli.v1"
 
-	emtUtils "github.com/tendermint/ethermint/cmd/utils"
+	emtUtils "github.com/zhuzeyu/pluto/cmd/utils"
 )
 
 func resetCmd(ctx *cli.Context) error {
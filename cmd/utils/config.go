@@ -1,17 +1,28 @@
 package utils
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"math/big"
 	"os"
+	"reflect"
+	"unicode"
 
+	"github.com/naoina/toml"
 	cli "gopkg.in/urfave/cli.v1"
 
 	ethUtils "github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 
+	abciApp "github.com/zhuzeyu/pluto/app"
 	"github.com/zhuzeyu/pluto/ethereum"
+	"github.com/zhuzeyu/pluto/strategies/gas"
+	"github.com/zhuzeyu/pluto/strategies/miner"
+	emtTypes "github.com/zhuzeyu/pluto/types"
 
 	rpcClient "github.com/tendermint/tendermint/rpc/lib/client"
 )
@@ -29,19 +40,66 @@ var (
 	GenesisTargetGasLimit = big.NewInt(100000000)
 )
 
+// These settings ensure that TOML keys use the same names as Go struct fields.
+var tomlSettings = toml.Config{
+	NormFieldName: func(rt reflect.Type, key string) string {
+		return key
+	},
+	FieldToKey: func(rt reflect.Type, field string) string {
+		return field
+	},
+	MissingField: func(rt reflect.Type, field string) error {
+		link := ""
+		if unicode.IsUpper(rune(rt.Name()[0])) && rt.PkgPath() != "main" {
+			link = fmt.Sprintf(", see https://godoc.org/%s#%s for available fields", rt.PkgPath(), rt.Name())
+		}
+		return fmt.Errorf("field '%s' is not defined in %s%s", field, rt.String(), link)
+	},
+}
+
 type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+// PlutoConfig holds the pluto specific node settings
+// #unstable
+type PlutoConfig struct {
+	// MinGasPrice is the lowest gas price of the txs accepted into the
+	// mempool. Nil accepts any gas price.
+	MinGasPrice *big.Int
+
+	// QueryAllow and QueryDeny are the patterns of the json-rpc methods
+	// that ABCI queries can and can't call
+	QueryAllow []string
+	QueryDeny  []string
+}
+
 type gethConfig struct {
 	Eth      eth.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
+	Pluto    PlutoConfig
 }
 
-// MakeFullNode creates a full go-ethereum node
+func loadConfig(file string, cfg *gethConfig) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	err = tomlSettings.NewDecoder(bufio.NewReader(f)).Decode(cfg)
+	// Add file name to errors that have a line number.
+	if _, ok := err.(*toml.LineError); ok {
+		err = errors.New(file + ", " + err.Error())
+	}
+	return err
+}
+
+// MakeFullNode creates a full go-ethereum node and returns it together with
+// the pluto settings
 // #unstable
-func MakeFullNode(ctx *cli.Context) *ethereum.Node {
+func MakeFullNode(ctx *cli.Context) (*ethereum.Node, *PlutoConfig) {
 	stack, cfg := makeConfigNode(ctx)
 
 	tendermintLAddr := ctx.GlobalString(TendermintAddrFlag.Name)
@@ -51,13 +109,21 @@ func MakeFullNode(ctx *cli.Context) *ethereum.Node {
 		ethUtils.Fatalf("Failed to register the ABCI application service: %v", err)
 	}
 
-	return stack
+	return stack, &cfg.Pluto
 }
 
 func makeConfigNode(ctx *cli.Context) (*ethereum.Node, gethConfig) {
 	cfg := gethConfig{
-		Eth:  eth.DefaultConfig,
-		Node: DefaultNodeConfig(),
+		Eth:   eth.DefaultConfig,
+		Node:  DefaultNodeConfig(),
+		Pluto: DefaultPlutoConfig(),
+	}
+
+	// Load config file.
+	if file := ctx.GlobalString(ConfigFileFlag.Name); file != "" {
+		if err := loadConfig(file, &cfg); err != nil {
+			ethUtils.Fatalf("%v", err)
+		}
 	}
 
 	ethUtils.SetNodeConfig(ctx, &cfg.Node)
@@ -70,17 +136,66 @@ func makeConfigNode(ctx *cli.Context) (*ethereum.Node, gethConfig) {
 	SetEthermintEthConfig(&cfg.Eth)
 	ethUtils.SetEthConfig(ctx, &stack.Node, &cfg.Eth)
 
+	SetPlutoConfig(ctx, &cfg.Pluto)
+
 	return stack, cfg
 }
 
+// DefaultPlutoConfig returns the default pluto settings
+// #unstable
+func DefaultPlutoConfig() PlutoConfig {
+	return PlutoConfig{
+		QueryAllow: abciApp.DefaultQueryAllow,
+		QueryDeny:  abciApp.DefaultQueryDeny,
+	}
+}
+
+// SetPlutoConfig applies the pluto flags on top of the config file
+// #unstable
+func SetPlutoConfig(ctx *cli.Context, cfg *PlutoConfig) {
+	if ctx.GlobalIsSet(ethUtils.MinerGasPriceFlag.Name) {
+		cfg.MinGasPrice = ethUtils.GlobalBig(ctx, ethUtils.MinerGasPriceFlag.Name)
+	}
+	if ctx.GlobalIsSet(QueryAllowFlag.Name) {
+		cfg.QueryAllow = abciApp.SplitMethods(ctx.GlobalString(QueryAllowFlag.Name))
+	}
+	if ctx.GlobalIsSet(QueryDenyFlag.Name) {
+		cfg.QueryDeny = abciApp.SplitMethods(ctx.GlobalString(QueryDenyFlag.Name))
+	}
+}
+
+// MakeStrategy creates the reward strategy selected in the genesis file and
+// the gas limit schedule of the genesis file, if any. They decide the
+// coinbase and the gas limit of the blocks, so they must not be node
+// settings.
+// #unstable
+func MakeStrategy(backend *ethereum.Backend) (*emtTypes.Strategy, error) {
+	reward := backend.Params().Reward
+	strategy := new(emtTypes.Strategy)
+	switch reward.Strategy {
+	case emtTypes.RewardStrategyFixed:
+		strategy.MinerRewardStrategy = miner.NewRewardConstant(reward.Address)
+	case emtTypes.RewardStrategyProposer:
+		strategy.MinerRewardStrategy = miner.NewRewardProposer(backend.RewardAddress)
+	case emtTypes.RewardStrategyRoundRobin:
+		strategy.MinerRewardStrategy = miner.NewRewardRoundRobin(backend.RewardAddress)
+	default:
+		return nil, fmt.Errorf("unknown reward strategy %q", reward.Strategy)
+	}
+	if gasLimits := backend.Params().GasLimits; len(gasLimits) > 0 {
+		strategy.Governor = gas.NewSchedule(gasLimits)
+	}
+	return strategy, nil
+}
+
 // DefaultNodeConfig returns the default configuration for a go-ethereum node
 // #unstable
 func DefaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
 	cfg.Version = params.Version
-	cfg.HTTPModules = append(cfg.HTTPModules, "eth")
-	cfg.WSModules = append(cfg.WSModules, "eth")
+	cfg.HTTPModules = append(cfg.HTTPModules, "eth", "pluto")
+	cfg.WSModules = append(cfg.WSModules, "eth", "pluto")
 	cfg.IPCPath = "geth.ipc"
 
 	emHome := os.Getenv(emHome)
@@ -103,8 +218,10 @@ func SetEthermintNodeConfig(cfg *node.Config) {
 func SetEthermintEthConfig(cfg *eth.Config) {
 	/*cfg.MaxPeers = 0
 	cfg.PowFake = true*/
-	cfg.Ethash.PowMode = 3
-
+	// eth.New always creates an ethash engine, which the backend replaces
+	// with the pluto engine right away, see ethereum.Engine. In full fake
+	// mode it never generates a DAG.
+	cfg.Ethash.PowMode = ethash.ModeFullFake
 }
 
 // MakeDataDir retrieves the currently requested data directory
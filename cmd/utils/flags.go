@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"strings"
+
 	"gopkg.in/urfave/cli.v1"
+
+	abciApp "github.com/zhuzeyu/pluto/app"
 )
 
 var (
@@ -55,6 +59,22 @@ var (
 		Value: GenesisTargetGasLimit.Uint64(),
 	}
 
+	// QueryAllowFlag sets the json-rpc methods that ABCI queries can call
+	// #unstable
+	QueryAllowFlag = cli.StringFlag{
+		Name:  "query.allow",
+		Usage: "Comma separated json-rpc methods ABCI queries can call, a trailing * matches a prefix",
+		Value: strings.Join(abciApp.DefaultQueryAllow, ","),
+	}
+
+	// QueryDenyFlag sets the json-rpc methods that ABCI queries can't call
+	// #unstable
+	QueryDenyFlag = cli.StringFlag{
+		Name:  "query.deny",
+		Usage: "Comma separated json-rpc methods ABCI queries can't call, a trailing * matches a prefix",
+		Value: strings.Join(abciApp.DefaultQueryDeny, ","),
+	}
+
 	// WithTendermintFlag asks to start Tendermint
 	// `tendermint init` and `tendermint node` when `ethermint init`
 	// and `ethermint` are invoked respectively.
@@ -30,6 +30,13 @@ func Setup(ctx *cli.Context) error {
 	return nil
 }
 
+// SetVerbosity changes the verbosity of the ethereum logging at runtime
+// #unstable
+func SetVerbosity(level int) error {
+	glogger.Verbosity(log.Lvl(level))
+	return nil
+}
+
 // ---------------------------
 // EthermintLogger - wraps the logger in tmlibs
 
@@ -3,7 +3,10 @@ package ethereum
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // We must implement our own net service since we don't have access to `internal/ethapi`
@@ -37,3 +40,145 @@ func (n *NetRPCService) PeerCount() hexutil.Uint {
 func (n *NetRPCService) Version() string {
 	return fmt.Sprintf("%d", n.networkVersion)
 }
+
+//----------------------------------------------------------------------
+// Pluto specific chain information
+
+// PublicPlutoAPI offers the pluto namespace
+// #unstable
+type PublicPlutoAPI struct {
+	b *Backend
+}
+
+// NewPublicPlutoAPI creates a new pluto API instance.
+// #unstable
+func NewPublicPlutoAPI(b *Backend) *PublicPlutoAPI {
+	return &PublicPlutoAPI{b}
+}
+
+// RPCReward is the json representation of a block reward
+type RPCReward struct {
+	Address common.Address `json:"address"`
+	Amount  *hexutil.Big   `json:"amount"`
+}
+
+// GetBlockRewards returns the rewards paid out in the given block
+// #unstable
+func (api *PublicPlutoAPI) GetBlockRewards(number rpc.BlockNumber) ([]RPCReward, error) {
+	rewards, err := readBlockRewards(api.b.Ethereum().ChainDb(), api.blockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RPCReward, 0, len(rewards))
+	for _, reward := range rewards {
+		result = append(result, RPCReward{reward.Address, (*hexutil.Big)(reward.Amount)})
+	}
+	return result, nil
+}
+
+// GetStakingLogs returns the logs of the staking events of the given block
+// that happen outside of any tx, like slashings. They belong to no tx, so
+// they have no receipt and are not seen by eth_getLogs.
+// #unstable
+func (api *PublicPlutoAPI) GetStakingLogs(number rpc.BlockNumber) ([]*ethTypes.Log, error) {
+	blockchain := api.b.Ethereum().BlockChain()
+	header := blockchain.GetHeaderByNumber(api.blockNumber(number))
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", api.blockNumber(number))
+	}
+	logs, err := readStakingLogs(api.b.Ethereum().ChainDb(), header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ethTypes.Log, 0, len(logs))
+	for i, log := range logs {
+		log.BlockNumber = header.Number.Uint64()
+		log.BlockHash = header.Hash()
+		log.Index = uint(i)
+		result = append(result, log)
+	}
+	return result, nil
+}
+
+// RPCFeeSplit is the json representation of a fee split
+type RPCFeeSplit struct {
+	Collected  *hexutil.Big `json:"collected"`
+	Burned     *hexutil.Big `json:"burned"`
+	Treasury   *hexutil.Big `json:"treasury"`
+	Validators *hexutil.Big `json:"validators"`
+	Coinbase   *hexutil.Big `json:"coinbase"`
+}
+
+func newRPCFeeSplit(split FeeSplit) RPCFeeSplit {
+	return RPCFeeSplit{
+		Collected:  (*hexutil.Big)(split.Collected),
+		Burned:     (*hexutil.Big)(split.Burned),
+		Treasury:   (*hexutil.Big)(split.Treasury),
+		Validators: (*hexutil.Big)(split.Validators),
+		Coinbase:   (*hexutil.Big)(split.Coinbase),
+	}
+}
+
+// FeeStats returns how the gas fees of the given block were routed, and the
+// totals of the chain up to that block
+// #unstable
+func (api *PublicPlutoAPI) FeeStats(number rpc.BlockNumber) (map[string]interface{}, error) {
+	stats, err := readFeeStats(api.b.Ethereum().ChainDb(), api.blockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	fees := api.b.Params().Fees
+	return map[string]interface{}{
+		"block": newRPCFeeSplit(stats.Block),
+		"total": newRPCFeeSplit(stats.Total),
+		"policy": map[string]interface{}{
+			"burnPercent":      hexutil.Uint64(fees.BurnPercent),
+			"treasuryPercent":  hexutil.Uint64(fees.TreasuryPercent),
+			"treasury":         fees.Treasury,
+			"validatorPercent": hexutil.Uint64(fees.ValidatorPercent),
+		},
+	}, nil
+}
+
+// TotalSupply returns the total supply of ether at the given block. It fails
+// on chains initialised without the supply counter.
+// #unstable
+func (api *PublicPlutoAPI) TotalSupply(number rpc.BlockNumber) (*hexutil.Big, error) {
+	blockchain := api.b.Ethereum().BlockChain()
+	block := blockchain.GetBlockByNumber(api.blockNumber(number))
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", api.blockNumber(number))
+	}
+	statedb, err := blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	supply, err := TotalSupply(statedb)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(supply), nil
+}
+
+// GetValidatorAddress returns the ethereum address registered for the
+// validator with the given tendermint address, or null if there is none
+// #unstable
+func (api *PublicPlutoAPI) GetValidatorAddress(validator hexutil.Bytes) (*common.Address, error) {
+	statedb, err := api.b.Ethereum().BlockChain().State()
+	if err != nil {
+		return nil, err
+	}
+	address, ok := rewardAddress(statedb, api.b.es.params, api.b.es.staking, validator)
+	if !ok {
+		return nil, nil
+	}
+	return &address, nil
+}
+
+// blockNumber resolves the latest and pending tags to the current block
+func (api *PublicPlutoAPI) blockNumber(number rpc.BlockNumber) uint64 {
+	if number < 0 {
+		return api.b.Ethereum().BlockChain().CurrentBlock().NumberU64()
+	}
+	return uint64(number)
+}
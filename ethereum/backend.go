@@ -3,7 +3,6 @@ package ethereum
 import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
-	"github.com/ethereum/go-ethereum/core/state"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/event"
@@ -14,6 +13,7 @@ import (
 	"github.com/tendermint/tendermint/mempool"
 	rpcClient "github.com/tendermint/tendermint/rpc/lib/client"
 
+	"github.com/zhuzeyu/pluto/staking"
 	plutoTypes "github.com/zhuzeyu/pluto/types"
 )
 
@@ -54,17 +54,32 @@ func NewBackend(ctx *node.ServiceContext, ethConfig *eth.Config,
 	if err != nil {
 		return nil, err
 	}
+	if err := installEngine(ethereum, es.engine); err != nil {
+		return nil, err
+	}
+
+	params, err := ReadParams(ethereum.ChainDb())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSupply(ethereum.BlockChain(), params); err != nil {
+		return nil, err
+	}
+
+	blockSize, err := readBlockSize(ethereum.ChainDb())
+	if err != nil {
+		return nil, err
+	}
 
 	es.SetEthereum(ethereum)
 	es.SetEthConfig(ethConfig)
+	es.SetParams(params)
+	es.maxBytes = blockSize.MaxBytes
 
 	// send special event to go-ethereum to switch homestead=true.
 	currentBlock := ethereum.BlockChain().CurrentBlock()
 	ethereum.EventMux().Post(core.ChainHeadEvent{currentBlock}) // nolint: vet, errcheck
 
-	// We don't need PoW/Uncle validation.
-	ethereum.BlockChain().SetValidator(&NullBlockProcessor{})
-
 	ethBackend := &Backend{
 		ethereum:  ethereum,
 		ethConfig: ethConfig,
@@ -80,12 +95,24 @@ func (b *Backend) Ethereum() *eth.Ethereum {
 	return b.ethereum
 }
 
+// Engine returns the consensus engine of the chain.
+// #unstable
+func (b *Backend) Engine() *Engine {
+	return b.es.Engine()
+}
+
 // Config returns the eth.Config.
 // #stable
 func (b *Backend) Config() *eth.Config {
 	return b.ethConfig
 }
 
+// Params returns the pluto chain parameters.
+// #unstable
+func (b *Backend) Params() *plutoTypes.Params {
+	return b.es.params
+}
+
 func (b *Backend) SetMemPool(memPool *mempool.Mempool) {
 	b.memPool = memPool
 }
@@ -93,35 +120,115 @@ func (b *Backend) SetMemPool(memPool *mempool.Mempool) {
 //----------------------------------------------------------------------
 // Handle block processing
 
-// DeliverTx appends a transaction to the current block
+// DeliverTx appends a transaction to the current block and returns its receipt,
+// which is nil if the tx didn't make it into the block
 // #stable
-func (b *Backend) DeliverTx(tx *ethTypes.Transaction) abciTypes.ResponseDeliverTx {
+func (b *Backend) DeliverTx(tx *ethTypes.Transaction) (abciTypes.ResponseDeliverTx, *ethTypes.Receipt) {
 	return b.es.DeliverTx(tx)
 }
 
-// AccumulateRewards accumulates the rewards based on the given strategy
+// AccumulateRewards splits the block reward and the fee share between the
+// validators of the last commit
+// #unstable
+func (b *Backend) AccumulateRewards() error {
+	return b.es.AccumulateRewards()
+}
+
+// SetLastCommit records the validators of the last commit, whose voting power
+// weights the rewards of the block
+// #unstable
+func (b *Backend) SetLastCommit(votes []abciTypes.VoteInfo) {
+	b.es.SetLastCommit(votes)
+}
+
+// RewardAddress returns the ethereum address that collects the rewards of the
+// validator with the given tendermint address
+// #unstable
+func (b *Backend) RewardAddress(validator []byte) (common.Address, bool) {
+	return b.es.RewardAddress(validator)
+}
+
+// SetCoinbase sets the coinbase of the block being built
 // #unstable
-func (b *Backend) AccumulateRewards(strategy *plutoTypes.Strategy) {
-	b.es.AccumulateRewards(strategy)
+func (b *Backend) SetCoinbase(coinbase common.Address) {
+	b.es.SetCoinbase(coinbase)
 }
 
-// Commit finalises the current block
+// InitChain registers the genesis validators with the staking keeper
+// #unstable
+func (b *Backend) InitChain(validators []abciTypes.ValidatorUpdate) {
+	b.es.InitChain(validators)
+}
+
+// HandleVotes tracks the signatures of the last block and returns the
+// validators jailed for downtime
+// #unstable
+func (b *Backend) HandleVotes(votes []abciTypes.VoteInfo) []*staking.Jail {
+	return b.es.HandleVotes(votes)
+}
+
+// SlashDoubleSign slashes and jails the staked validator with the given
+// tendermint address. It returns nil if the address has no stake.
+// #unstable
+func (b *Backend) SlashDoubleSign(address []byte) *staking.Slash {
+	return b.es.SlashDoubleSign(address)
+}
+
+// EndBlock applies the end of block staking changes and returns the
+// resulting validator updates
+// #unstable
+func (b *Backend) EndBlock() []abciTypes.ValidatorUpdate {
+	return b.es.EndBlock()
+}
+
+// ContractValidators returns the validator set held by the validator contract
+// configured in the genesis file
+// #unstable
+func (b *Backend) ContractValidators() ([]abciTypes.ValidatorUpdate, error) {
+	return b.es.ContractValidators()
+}
+
+// Commit finalises the current block and returns its app hash
 // #unstable
 func (b *Backend) Commit(receiver common.Address) (common.Hash, error) {
 	return b.es.Commit(receiver)
 }
 
+// WriteValidators stores the validator set after the given block, so it can
+// be restored after a restart
+// #unstable
+func (b *Backend) WriteValidators(number uint64, validators []abciTypes.ValidatorUpdate) error {
+	return writeValidators(b.ethereum.ChainDb(), number, validators)
+}
+
+// Validators loads the validator set stored after the given block. It
+// returns false if there is none.
+// #unstable
+func (b *Backend) Validators(number uint64) ([]abciTypes.ValidatorUpdate, bool, error) {
+	return readValidators(b.ethereum.ChainDb(), number)
+}
+
 // InitEthState initializes the EthState
 // #unstable
 func (b *Backend) InitEthState(receiver common.Address) error {
 	return b.es.ResetWorkState(receiver)
 }
 
+// Rollback rewinds the ethereum chain to the given height, e.g. to undo a
+// block that tendermint didn't persist before a crash
+// #unstable
+func (b *Backend) Rollback(height uint64, receiver common.Address) error {
+	return b.es.Rollback(height, receiver)
+}
+
 // UpdateHeaderWithTimeInfo uses the tendermint header to update the ethereum header
+// and links it to the tendermint block with the given hash, see TendermintLink.
+// The coinbase is set to the registered address of the proposer.
 // #unstable
-func (b *Backend) UpdateHeaderWithTimeInfo(tmHeader *abciTypes.Header) {
-	b.es.UpdateHeaderWithTimeInfo(b.ethereum.APIBackend.ChainConfig(), uint64(tmHeader.Time.Unix()),
-		uint64(tmHeader.GetNumTxs()))
+func (b *Backend) UpdateHeaderWithTimeInfo(tmHeader *abciTypes.Header, blockHash []byte) {
+	b.es.UpdateHeaderWithTimeInfo(uint64(tmHeader.Time.Unix()), uint64(tmHeader.GetNumTxs()),
+		tmHeader.GetProposerAddress(), uint64(tmHeader.GetHeight()), blockHash,
+		tmHeader.GetLastCommitHash())
 }
 
 // GasLimit returns the maximum gas per block
@@ -129,6 +236,26 @@ func (b *Backend) GasLimit() uint64 {
 	return b.es.GasLimit()
 }
 
+// SetBlockSize sets the block size consensus params received in InitChain.
+// A positive MaxGas is the gas limit of the blocks from the current one on.
+// #unstable
+func (b *Backend) SetBlockSize(blockSize BlockSize) error {
+	return b.es.SetBlockSize(blockSize)
+}
+
+// UpdateMaxGas changes the gas limit from the next block on. It returns the
+// block size consensus params to report to tendermint in EndBlock.
+// #unstable
+func (b *Backend) UpdateMaxGas(maxGas int64) BlockSize {
+	return b.es.UpdateMaxGas(maxGas)
+}
+
+// BlockSize returns the current block size consensus params
+// #unstable
+func (b *Backend) BlockSize() BlockSize {
+	return b.es.BlockSize()
+}
+
 //----------------------------------------------------------------------
 // Implements: node.Service
 
@@ -148,6 +275,12 @@ func (b *Backend) APIs() []rpc.API {
 		}
 		retApis = append(retApis, v)
 	}
+	retApis = append(retApis, rpc.API{
+		Namespace: "pluto",
+		Version:   "1.0",
+		Service:   NewPublicPlutoAPI(b),
+		Public:    true,
+	})
 	return retApis
 }
 
@@ -161,7 +294,9 @@ func (b *Backend) Start(_ *p2p.Server) error {
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (b *Backend) Stop() error {
-	b.txSub.Unsubscribe()
+	if b.txSub != nil {
+		b.txSub.Unsubscribe()
+	}
 	b.ethereum.Stop() // nolint: errcheck
 	return nil
 }
@@ -171,19 +306,3 @@ func (b *Backend) Stop() error {
 func (b *Backend) Protocols() []p2p.Protocol {
 	return nil
 }
-
-//----------------------------------------------------------------------
-// We need a block processor that just ignores PoW and uncles and so on
-
-// NullBlockProcessor does not validate anything
-type NullBlockProcessor struct{}
-
-// ValidateBody does not validate anything
-func (v *NullBlockProcessor) ValidateBody(block *ethTypes.Block) error {
-	return nil
-}
-
-// ValidateState does not validate anything
-func (v *NullBlockProcessor) ValidateState(block, parent *ethTypes.Block, state *state.StateDB, receipts ethTypes.Receipts, usedGas uint64) error {
-	return nil
-}
@@ -0,0 +1,205 @@
+package ethereum
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+//----------------------------------------------------------------------
+// Pluto specific data kept in the ethereum chain database
+
+var (
+	paramsKey    = []byte("pluto-params")
+	blockSizeKey = []byte("pluto-blocksize")
+
+	blockRewardsPrefix = []byte("pluto-r") // blockRewardsPrefix + num (uint64 big endian) -> rewards
+	feeStatsPrefix     = []byte("pluto-f") // feeStatsPrefix + num (uint64 big endian) -> fee stats
+	validatorsPrefix   = []byte("pluto-v") // validatorsPrefix + num (uint64 big endian) -> validator set
+	stakingLogsPrefix  = []byte("pluto-s") // stakingLogsPrefix + num (uint64 big endian) -> staking logs
+)
+
+// encodeBlockNumber encodes a block number as big endian uint64
+func encodeBlockNumber(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+// blockRewardsKey = blockRewardsPrefix + num (uint64 big endian)
+func blockRewardsKey(number uint64) []byte {
+	return append(append([]byte{}, blockRewardsPrefix...), encodeBlockNumber(number)...)
+}
+
+// feeStatsKey = feeStatsPrefix + num (uint64 big endian)
+func feeStatsKey(number uint64) []byte {
+	return append(append([]byte{}, feeStatsPrefix...), encodeBlockNumber(number)...)
+}
+
+// validatorsKey = validatorsPrefix + num (uint64 big endian)
+func validatorsKey(number uint64) []byte {
+	return append(append([]byte{}, validatorsPrefix...), encodeBlockNumber(number)...)
+}
+
+// stakingLogsKey = stakingLogsPrefix + num (uint64 big endian)
+func stakingLogsKey(number uint64) []byte {
+	return append(append([]byte{}, stakingLogsPrefix...), encodeBlockNumber(number)...)
+}
+
+// WriteParams stores the chain parameters
+// #unstable
+func WriteParams(db ethdb.Putter, params *plutoTypes.Params) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return db.Put(paramsKey, data)
+}
+
+// ReadParams loads the chain parameters.
+// Chains initialised without a "pluto" genesis section get the defaults.
+// #unstable
+func ReadParams(db ethdb.Database) (*plutoTypes.Params, error) {
+	data, _ := db.Get(paramsKey)
+	if len(data) == 0 {
+		return plutoTypes.DefaultParams(), nil
+	}
+	params := plutoTypes.DefaultParams()
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, err
+	}
+	// params written by older versions may have validator keys that are
+	// not normalized
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// BlockSize are the tendermint block size consensus params. MaxGas is the
+// gas limit of the ethereum blocks, unless it is not positive.
+// #unstable
+type BlockSize struct {
+	MaxBytes int64
+	MaxGas   int64
+}
+
+// writeBlockSize stores the block size consensus params of InitChain. Only
+// MaxBytes is used, the gas limit of a block is in its header.
+func writeBlockSize(db ethdb.Putter, blockSize BlockSize) error {
+	data, err := json.Marshal(blockSize)
+	if err != nil {
+		return err
+	}
+	return db.Put(blockSizeKey, data)
+}
+
+// readBlockSize loads the block size consensus params of InitChain, which are
+// zero before
+func readBlockSize(db ethdb.Database) (BlockSize, error) {
+	var blockSize BlockSize
+	data, _ := db.Get(blockSizeKey)
+	if len(data) == 0 {
+		return blockSize, nil
+	}
+	err := json.Unmarshal(data, &blockSize)
+	return blockSize, err
+}
+
+// writeBlockRewards stores the rewards paid out in the given block
+func writeBlockRewards(db ethdb.Putter, number uint64, rewards []*Reward) error {
+	data, err := rlp.EncodeToBytes(rewards)
+	if err != nil {
+		return err
+	}
+	return db.Put(blockRewardsKey(number), data)
+}
+
+// readBlockRewards loads the rewards paid out in the given block
+func readBlockRewards(db ethdb.Database, number uint64) ([]*Reward, error) {
+	data, _ := db.Get(blockRewardsKey(number))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var rewards []*Reward
+	if err := rlp.DecodeBytes(data, &rewards); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// writeFeeStats stores the fee stats of the given block
+func writeFeeStats(db ethdb.Putter, number uint64, stats *FeeStats) error {
+	data, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		return err
+	}
+	return db.Put(feeStatsKey(number), data)
+}
+
+// readFeeStats loads the fee stats of the given block. Blocks committed
+// before fee routing have empty stats.
+func readFeeStats(db ethdb.Database, number uint64) (*FeeStats, error) {
+	data, _ := db.Get(feeStatsKey(number))
+	if len(data) == 0 {
+		return &FeeStats{Block: newFeeSplit(), Total: newFeeSplit()}, nil
+	}
+	stats := new(FeeStats)
+	if err := rlp.DecodeBytes(data, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// writeValidators stores the validator set after the given block
+func writeValidators(db ethdb.Putter, number uint64, validators []abciTypes.ValidatorUpdate) error {
+	data, err := json.Marshal(validators)
+	if err != nil {
+		return err
+	}
+	return db.Put(validatorsKey(number), data)
+}
+
+// readValidators loads the validator set after the given block. It returns
+// false for blocks committed without one.
+func readValidators(db ethdb.Database, number uint64) ([]abciTypes.ValidatorUpdate, bool, error) {
+	data, _ := db.Get(validatorsKey(number))
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+	var validators []abciTypes.ValidatorUpdate
+	if err := json.Unmarshal(data, &validators); err != nil {
+		return nil, false, err
+	}
+	return validators, true, nil
+}
+
+// writeStakingLogs stores the logs of the staking events of the given block
+func writeStakingLogs(db ethdb.Putter, number uint64, logs []*ethTypes.Log) error {
+	data, err := rlp.EncodeToBytes(logs)
+	if err != nil {
+		return err
+	}
+	return db.Put(stakingLogsKey(number), data)
+}
+
+// readStakingLogs loads the logs of the staking events of the given block.
+// Only their address, topics and data are stored.
+func readStakingLogs(db ethdb.Database, number uint64) ([]*ethTypes.Log, error) {
+	data, _ := db.Get(stakingLogsKey(number))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var logs []*ethTypes.Log
+	if err := rlp.DecodeBytes(data, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,242 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+//----------------------------------------------------------------------
+// Engine is the consensus engine of the ethereum chain. The blocks are agreed
+// on by tendermint, so there is nothing to mine or seal: a header is valid if
+// it follows its parent and is linked to the tendermint block of the same
+// height, see linkHeader. There are no uncles, and the block rewards are paid
+// by the strategy instead of the engine.
+//
+// The Engine prepares the headers of the work state and assembles and
+// verifies its blocks before they are inserted into the BlockChain. The
+// BlockChain validates them with it through blockValidator, and blocks
+// processed by go-ethereum are finalized by it, so no ethash block rewards
+// are paid.
+//
+// eth.New hardwires an ethash engine into unexported fields of the service
+// and its BlockChain. installEngine replaces it with the Engine before the
+// node starts, so InsertChain, the EVM, the tracers and the engine APIs use
+// the Engine only. The miner keeps ethash, it is never started.
+
+var (
+	errUnlinkedHeader    = errors.New("header is not linked to a tendermint block")
+	errInvalidDifficulty = errors.New("invalid difficulty")
+	errUnclesNotAllowed  = errors.New("uncles not allowed")
+	errSealNotSupported  = errors.New("blocks are sealed by tendermint")
+)
+
+// Engine implements consensus.Engine on top of tendermint
+// #unstable
+type Engine struct{}
+
+// NewEngine creates the consensus engine
+// #unstable
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Author returns the coinbase of the header, which is the proposer of the
+// tendermint block if it registered an address
+func (e *Engine) Author(header *ethTypes.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader checks a header against its parent in the chain
+func (e *Engine) VerifyHeader(chain consensus.ChainReader, header *ethTypes.Header, seal bool) error {
+	return e.verifyHeader(chain, header, nil)
+}
+
+// VerifyHeaders checks a batch of headers concurrently. The parent of a header
+// is either the previous one of the batch or in the chain.
+func (e *Engine) VerifyHeaders(chain consensus.ChainReader, headers []*ethTypes.Header,
+	seals []bool) (chan<- struct{}, <-chan error) {
+
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+	go func() {
+		for i, header := range headers {
+			err := e.verifyHeader(chain, header, headers[:i])
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks a header. The last of the parents, if any, is the
+// parent of the header, otherwise it is looked up in the chain.
+func (e *Engine) verifyHeader(chain consensus.ChainReader, header *ethTypes.Header,
+	parents []*ethTypes.Header) error {
+
+	if header.Number == nil {
+		return consensus.ErrInvalidNumber
+	}
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+
+	var parent *ethTypes.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else if number > 0 {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Number.Uint64()+1 != number {
+		return consensus.ErrInvalidNumber
+	}
+
+	if header.Difficulty == nil || header.Difficulty.Cmp(BlockDifficulty) != 0 {
+		return errInvalidDifficulty
+	}
+	if header.UncleHash != ethTypes.EmptyUncleHash {
+		return errUnclesNotAllowed
+	}
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("gas used %d above gas limit %d", header.GasUsed, header.GasLimit)
+	}
+
+	link, err := HeaderLink(header)
+	if err != nil {
+		return errUnlinkedHeader
+	}
+	if link.Height != number {
+		return fmt.Errorf("header of block %d linked to tendermint height %d", number, link.Height)
+	}
+	if len(link.BlockHash) == 0 {
+		return errUnlinkedHeader
+	}
+	return nil
+}
+
+// VerifyUncles rejects any uncles
+func (e *Engine) VerifyUncles(chain consensus.ChainReader, block *ethTypes.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errUnclesNotAllowed
+	}
+	return nil
+}
+
+// VerifySeal does nothing, the block was committed by tendermint
+func (e *Engine) VerifySeal(chain consensus.ChainReader, header *ethTypes.Header) error {
+	return nil
+}
+
+// Prepare sets the difficulty of the header
+func (e *Engine) Prepare(chain consensus.ChainReader, header *ethTypes.Header) error {
+	header.Difficulty = new(big.Int).Set(BlockDifficulty)
+	return nil
+}
+
+// Finalize assembles the block. Unlike ethash it pays no rewards.
+func (e *Engine) Finalize(chain consensus.ChainReader, header *ethTypes.Header, state *state.StateDB,
+	txs []*ethTypes.Transaction, uncles []*ethTypes.Header, receipts []*ethTypes.Receipt) (*ethTypes.Block, error) {
+
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	return ethTypes.NewBlock(header, txs, nil, receipts), nil
+}
+
+// SealHash returns the hash of the header, there is no seal
+func (e *Engine) SealHash(header *ethTypes.Header) common.Hash {
+	return header.Hash()
+}
+
+// Seal always fails, the blocks are made by tendermint
+func (e *Engine) Seal(chain consensus.ChainReader, block *ethTypes.Block,
+	results chan<- *ethTypes.Block, stop <-chan struct{}) error {
+
+	return errSealNotSupported
+}
+
+// CalcDifficulty returns the constant difficulty
+func (e *Engine) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *ethTypes.Header) *big.Int {
+	return new(big.Int).Set(BlockDifficulty)
+}
+
+// APIs returns no APIs
+func (e *Engine) APIs(chain consensus.ChainReader) []rpc.API {
+	return nil
+}
+
+// Close does nothing
+func (e *Engine) Close() error {
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// installEngine makes engine the only engine of the ethereum service and
+// closes the ethash engine created by eth.New. The engine fields are
+// unexported and only read when used, so they are overwritten in place.
+func installEngine(ethereum *eth.Ethereum, engine consensus.Engine) error {
+	ethash := ethereum.Engine()
+	blockchain := ethereum.BlockChain()
+	headerChain, err := getField(blockchain, "hc")
+	if err != nil {
+		return err
+	}
+	for _, target := range []interface{}{ethereum, blockchain, headerChain} {
+		if err := setField(target, "engine", engine); err != nil {
+			return err
+		}
+	}
+	return ethash.Close()
+}
+
+// field returns the settable field name of the struct ptr points to
+func field(ptr interface{}, name string) (reflect.Value, error) {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%T is no pointer to a struct", ptr)
+	}
+	f := value.Elem().FieldByName(name)
+	if !f.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%T has no field %s", ptr, name)
+	}
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem(), nil
+}
+
+// getField returns the value of a possibly unexported field
+func getField(ptr interface{}, name string) (interface{}, error) {
+	f, err := field(ptr, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.Interface(), nil
+}
+
+// setField sets a possibly unexported field
+func setField(ptr interface{}, name string, value interface{}) error {
+	f, err := field(ptr, name)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(value)
+	if !v.Type().AssignableTo(f.Type()) {
+		return fmt.Errorf("%T can't be assigned to %T.%s", value, ptr, name)
+	}
+	f.Set(v)
+	return nil
+}
@@ -0,0 +1,121 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/params"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// testChain is a consensus.ChainReader over a few headers
+type testChain map[common.Hash]*ethTypes.Header
+
+func (c testChain) Config() *params.ChainConfig     { return params.TestChainConfig }
+func (c testChain) CurrentHeader() *ethTypes.Header { return nil }
+
+func (c testChain) GetHeader(hash common.Hash, number uint64) *ethTypes.Header {
+	if header, ok := c[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c testChain) GetHeaderByNumber(number uint64) *ethTypes.Header {
+	for _, header := range c {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c testChain) GetHeaderByHash(hash common.Hash) *ethTypes.Header { return c[hash] }
+
+func (c testChain) GetBlock(hash common.Hash, number uint64) *ethTypes.Block { return nil }
+
+// testHeader returns a header linked to a tendermint block that follows parent
+func testHeader(t *testing.T, parent *ethTypes.Header) *ethTypes.Header {
+	number := new(big.Int).Add(parent.Number, big.NewInt(1))
+	header := &ethTypes.Header{
+		ParentHash: parent.Hash(),
+		Number:     number,
+		UncleHash:  ethTypes.EmptyUncleHash,
+		GasLimit:   8000000,
+		GasUsed:    21000,
+	}
+	if err := linkHeader(header, number.Uint64(), number.Bytes(), nil); err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+func TestVerifyHeader(t *testing.T) {
+	genesis := &ethTypes.Header{Number: new(big.Int), Difficulty: big.NewInt(0x40)}
+	chain := testChain{genesis.Hash(): genesis}
+
+	tests := []struct {
+		name   string
+		modify func(header *ethTypes.Header)
+		err    error // nil for any error if fails is set
+		fails  bool
+	}{
+		{"linked header", func(header *ethTypes.Header) {}, nil, false},
+		{"no number", func(header *ethTypes.Header) { header.Number = nil }, consensus.ErrInvalidNumber, true},
+		{"unknown parent", func(header *ethTypes.Header) { header.ParentHash = common.Hash{1} },
+			consensus.ErrUnknownAncestor, true},
+		{"wrong number", func(header *ethTypes.Header) { header.Number = big.NewInt(2) },
+			consensus.ErrUnknownAncestor, true},
+		{"pow difficulty", func(header *ethTypes.Header) { header.Difficulty = big.NewInt(0x40) },
+			errInvalidDifficulty, true},
+		{"no difficulty", func(header *ethTypes.Header) { header.Difficulty = nil },
+			errInvalidDifficulty, true},
+		{"uncles", func(header *ethTypes.Header) { header.UncleHash = common.Hash{1} },
+			errUnclesNotAllowed, true},
+		{"gas above limit", func(header *ethTypes.Header) { header.GasUsed = header.GasLimit + 1 }, nil, true},
+		{"no link", func(header *ethTypes.Header) { header.Extra = nil }, errUnlinkedHeader, true},
+		{"link without block hash", func(header *ethTypes.Header) {
+			if err := linkHeader(header, 1, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}, errUnlinkedHeader, true},
+		{"link to another height", func(header *ethTypes.Header) {
+			if err := linkHeader(header, 2, []byte{2}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}, nil, true},
+	}
+	engine := NewEngine()
+	for _, test := range tests {
+		header := testHeader(t, genesis)
+		test.modify(header)
+		err := engine.VerifyHeader(chain, header, false)
+		switch {
+		case !test.fails && err != nil:
+			t.Errorf("%s: expected a valid header, got %v", test.name, err)
+		case test.fails && err == nil:
+			t.Errorf("%s: expected an invalid header", test.name)
+		case test.err != nil && err != test.err:
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	// headers in the chain are valid, the parent of a batch may be in it
+	child := testHeader(t, genesis)
+	grandchild := testHeader(t, child)
+	if err := engine.VerifyHeader(chain, genesis, false); err != nil {
+		t.Errorf("expected a known header to be valid, got %v", err)
+	}
+	_, results := engine.VerifyHeaders(chain, []*ethTypes.Header{child, grandchild}, nil)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("batch header %d: expected a valid header, got %v", i, err)
+		}
+	}
+	if err := engine.VerifyHeader(chain, grandchild, false); err != consensus.ErrUnknownAncestor {
+		t.Errorf("expected %v without the parent in the chain, got %v", consensus.ErrUnknownAncestor, err)
+	}
+}
@@ -0,0 +1,80 @@
+package ethereum
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+//----------------------------------------------------------------------
+// A tendermint tx carries either a single RLP encoded ethereum tx or an
+// envelope of several. An envelope starts with a version byte followed by
+// the RLP list of the txs. An RLP encoded tx always starts with a list
+// prefix of 0xc0 or more, so the two can't be mistaken for each other.
+
+// EnvelopeVersion is the version byte of the current envelope format
+// #unstable
+const EnvelopeVersion byte = 0x01
+
+// MaxEnvelopeTxs is the maximum number of txs in an envelope
+// #unstable
+const MaxEnvelopeTxs = 256
+
+var (
+	errEmptyTx            = errors.New("empty tx")
+	errEmptyEnvelope      = errors.New("empty tx envelope")
+	errEnvelopeTooLarge   = errors.New("too many txs in envelope")
+	errUnknownEnvelopeVer = errors.New("unknown tx envelope version")
+)
+
+// IsEnvelope tells if a tendermint tx is an envelope
+// #unstable
+func IsEnvelope(data []byte) bool {
+	return len(data) > 0 && data[0] < 0xc0
+}
+
+// EncodeTxs encodes the txs for tendermint: a single tx as is, several in an
+// envelope
+// #unstable
+func EncodeTxs(txs []*ethTypes.Transaction) ([]byte, error) {
+	if len(txs) == 1 {
+		return rlp.EncodeToBytes(txs[0])
+	}
+	data, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{EnvelopeVersion}, data...), nil
+}
+
+// DecodeTxs decodes a tendermint tx into the ethereum txs it carries
+// #unstable
+func DecodeTxs(data []byte) ([]*ethTypes.Transaction, error) {
+	if len(data) == 0 {
+		return nil, errEmptyTx
+	}
+	if !IsEnvelope(data) {
+		tx := new(ethTypes.Transaction)
+		if err := rlp.DecodeBytes(data, tx); err != nil {
+			return nil, err
+		}
+		return []*ethTypes.Transaction{tx}, nil
+	}
+
+	if data[0] != EnvelopeVersion {
+		return nil, errUnknownEnvelopeVer
+	}
+	var txs []*ethTypes.Transaction
+	if err := rlp.DecodeBytes(data[1:], &txs); err != nil {
+		return nil, err
+	}
+	switch {
+	case len(txs) == 0:
+		return nil, errEmptyEnvelope
+	case len(txs) > MaxEnvelopeTxs:
+		return nil, errEnvelopeTooLarge
+	}
+	return txs, nil
+}
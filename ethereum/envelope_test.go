@@ -0,0 +1,78 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func newTestTx(nonce uint64) *ethTypes.Transaction {
+	return ethTypes.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1),
+		21000, big.NewInt(1), nil)
+}
+
+func TestEncodeDecodeTxs(t *testing.T) {
+	for _, n := range []int{1, 2, MaxEnvelopeTxs} {
+		txs := make([]*ethTypes.Transaction, n)
+		for i := range txs {
+			txs[i] = newTestTx(uint64(i))
+		}
+		data, err := EncodeTxs(txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsEnvelope(data) != (n > 1) {
+			t.Errorf("%d txs: expected envelope %v", n, n > 1)
+		}
+
+		decoded, err := DecodeTxs(data)
+		if err != nil {
+			t.Fatalf("%d txs: %v", n, err)
+		}
+		if len(decoded) != n {
+			t.Fatalf("%d txs: decoded %d", n, len(decoded))
+		}
+		for i := range txs {
+			if decoded[i].Hash() != txs[i].Hash() {
+				t.Errorf("%d txs: tx %d differs", n, i)
+			}
+		}
+	}
+}
+
+func TestDecodeInvalidTxs(t *testing.T) {
+	tooMany := make([]*ethTypes.Transaction, MaxEnvelopeTxs+1)
+	for i := range tooMany {
+		tooMany[i] = newTestTx(uint64(i))
+	}
+	tooLarge, err := EncodeTxs(tooMany)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := EncodeTxs([]*ethTypes.Transaction{newTestTx(0), newTestTx(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownVersion := append([]byte{EnvelopeVersion + 1}, envelope[1:]...)
+
+	cases := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"empty":           {nil, errEmptyTx},
+		"empty envelope":  {[]byte{EnvelopeVersion, 0xc0}, errEmptyEnvelope},
+		"too large":       {tooLarge, errEnvelopeTooLarge},
+		"unknown version": {unknownVersion, errUnknownEnvelopeVer},
+	}
+	for name, c := range cases {
+		if _, err := DecodeTxs(c.data); err != c.err {
+			t.Errorf("%s: expected %v, got %v", name, c.err, err)
+		}
+	}
+
+	if _, err := DecodeTxs([]byte{0xc1, 0x00}); err == nil {
+		t.Error("expected an error decoding garbage")
+	}
+}
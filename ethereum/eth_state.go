@@ -1,21 +1,23 @@
 package ethereum
 
 import (
+	"fmt"
 	"math/big"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 
 	abciTypes "github.com/tendermint/tendermint/abci/types"
 
+	"github.com/zhuzeyu/pluto/staking"
 	plutoTypes "github.com/zhuzeyu/pluto/types"
 )
 
@@ -35,6 +37,13 @@ const errorCode = 1
 type EthState struct {
 	ethereum  *eth.Ethereum
 	ethConfig *eth.Config
+	params    *plutoTypes.Params
+	staking   *staking.Keeper
+	engine    *Engine
+	processor *blockProcessor // set with SetEthereum
+
+	maxBytes   int64 // block size consensus param, the gas limit is in the headers
+	nextMaxGas int64 // gas limit from the next block on, zero if unchanged
 
 	mtx  sync.Mutex
 	work workState // latest working state
@@ -45,52 +54,169 @@ func NewEthState() *EthState {
 	return &EthState{
 		ethereum:  nil, // set with SetEthereum
 		ethConfig: nil, // set with SetEthConfig
+		params:    plutoTypes.DefaultParams(),
+		staking:   staking.NewKeeper(plutoTypes.DefaultParams()),
+		engine:    NewEngine(),
 	}
 }
 
 func (es *EthState) SetEthereum(ethereum *eth.Ethereum) {
 	es.ethereum = ethereum
+
+	// InsertChain stores the results of the work state, see blockProcessor,
+	// and validates the blocks with the pluto engine
+	blockchain := ethereum.BlockChain()
+	es.processor = newBlockProcessor(core.NewStateProcessor(blockchain.Config(),
+		blockchain, es.engine))
+	blockchain.SetProcessor(es.processor)
+	blockchain.SetValidator(newBlockValidator(blockchain, es.engine))
 }
 
 func (es *EthState) SetEthConfig(ethConfig *eth.Config) {
 	es.ethConfig = ethConfig
 }
 
-// Execute the transaction.
-func (es *EthState) DeliverTx(tx *ethTypes.Transaction) abciTypes.ResponseDeliverTx {
+func (es *EthState) SetParams(params *plutoTypes.Params) {
+	es.params = params
+	es.staking = staking.NewKeeper(params)
+}
+
+// Execute the transaction. The receipt is nil if the tx didn't make it into
+// the block.
+func (es *EthState) DeliverTx(tx *ethTypes.Transaction) (abciTypes.ResponseDeliverTx, *ethTypes.Receipt) {
 	es.mtx.Lock()
 	defer es.mtx.Unlock()
 
-	blockchain := es.ethereum.BlockChain()
+	if minGasPrice := es.params.GetMinGasPrice(); tx.GasPrice().Cmp(minGasPrice) < 0 {
+		return abciTypes.ResponseDeliverTx{
+			Code: errorCode,
+			Log:  fmt.Sprintf("gas price %v below the minimum of %v", tx.GasPrice(), minGasPrice),
+		}, nil
+	}
+
+	chain := es.chainContext()
 	chainConfig := es.ethereum.APIBackend.ChainConfig()
 	blockHash := common.Hash{}
-	return es.work.deliverTx(blockchain, es.ethConfig, chainConfig, blockHash, tx)
+	res := es.work.deliverTx(chain, es.ethConfig, chainConfig, blockHash, tx)
+	if res.IsErr() {
+		return res, nil
+	}
+	receipt := es.work.receipts[len(es.work.receipts)-1]
+	return es.work.deliverStakingTx(chainConfig, es.staking, tx), receipt
 }
 
 // Accumulate validator rewards.
-func (es *EthState) AccumulateRewards(strategy *plutoTypes.Strategy) {
+func (es *EthState) AccumulateRewards() error {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	return es.work.accumulateRewards(es.params, es.rewardAddress)
+}
+
+// Record the validators of the last commit, whose voting power weights the
+// rewards of the block being built.
+func (es *EthState) SetLastCommit(votes []abciTypes.VoteInfo) {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	es.work.votes = votes
+}
+
+// Look up the reward address of a validator in the latest working state.
+func (es *EthState) RewardAddress(validator []byte) (common.Address, bool) {
 	es.mtx.Lock()
 	defer es.mtx.Unlock()
 
-	es.work.accumulateRewards(strategy)
+	return es.rewardAddress(validator)
+}
+
+func (es *EthState) rewardAddress(validator []byte) (common.Address, bool) {
+	return rewardAddress(es.work.state, es.params, es.staking, validator)
 }
 
-// Commit and reset the work.
+// Set the coinbase of the block being built.
+func (es *EthState) SetCoinbase(coinbase common.Address) {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	es.work.header.Coinbase = coinbase
+}
+
+// Register the genesis validators with the staking keeper.
+func (es *EthState) InitChain(validators []abciTypes.ValidatorUpdate) {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	es.staking.InitChain(es.work.state, validators, es.params)
+}
+
+// Track the signatures of the last block and jail validators that are down.
+func (es *EthState) HandleVotes(votes []abciTypes.VoteInfo) []*staking.Jail {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	return es.staking.HandleVotes(es.work.state, es.work.header.Number.Uint64(), votes)
+}
+
+// Slash and jail a validator caught double signing.
+func (es *EthState) SlashDoubleSign(address []byte) *staking.Slash {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	slash := es.staking.SlashDoubleSign(es.work.state, address)
+	if slash != nil {
+		if err := es.work.subSupply(slash.Amount); err != nil {
+			log.Error("Failed to account slashed stake in the supply", "err", err)
+		}
+		es.work.stakingLogs = append(es.work.stakingLogs, slash.Log())
+	}
+	return slash
+}
+
+// Apply the end of block staking changes and return the validator updates.
+func (es *EthState) EndBlock() []abciTypes.ValidatorUpdate {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	return es.staking.EndBlock(es.work.state, es.work.header.Number.Uint64())
+}
+
+// AppHash returns the app hash of a block, which is its state root. Unlike
+// the block hash it doesn't depend on header fields like time and difficulty.
+func AppHash(block *ethTypes.Block) common.Hash {
+	return block.Root()
+}
+
+// Commit and reset the work. Returns the app hash of the new block.
 func (es *EthState) Commit(receiver common.Address) (common.Hash, error) {
 	es.mtx.Lock()
 	defer es.mtx.Unlock()
 
-	blockHash, err := es.work.commit(es.ethereum.BlockChain(), es.ethereum.ChainDb())
+	appHash, err := es.work.commit(es.ethereum.BlockChain(), es.engine, es.processor,
+		es.ethereum.ChainDb())
 	if err != nil {
 		return common.Hash{}, err
 	}
 
 	err = es.resetWorkState(receiver)
+	es.nextMaxGas = 0
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	return blockHash, err
+	return appHash, err
+}
+
+// Rewind the blockchain to the given height and reset the work on top of it.
+func (es *EthState) Rollback(height uint64, receiver common.Address) error {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	if err := es.ethereum.BlockChain().SetHead(height); err != nil {
+		return err
+	}
+	es.nextMaxGas = 0
+	return es.resetWorkState(receiver)
 }
 
 func (es *EthState) ResetWorkState(receiver common.Address) error {
@@ -109,30 +235,93 @@ func (es *EthState) resetWorkState(receiver common.Address) error {
 	}
 
 	currentBlock := blockchain.CurrentBlock()
-	ethHeader := newBlockHeader(receiver, currentBlock)
+	ethHeader := newBlockHeader(receiver, currentBlock, uint64(es.nextMaxGas))
+	if err := es.engine.Prepare(blockchain, ethHeader); err != nil {
+		return err
+	}
 
 	es.work = workState{
 		header:       ethHeader,
 		parent:       currentBlock,
 		state:        state,
 		txIndex:      0,
+		fees:         newFeeSplit(),
 		totalUsedGas: new(uint64),
 		gp:           new(core.GasPool).AddGas(ethHeader.GasLimit),
 	}
 	return nil
 }
 
-func (es *EthState) UpdateHeaderWithTimeInfo(
-	config *params.ChainConfig, parentTime uint64, numTx uint64) {
+func (es *EthState) UpdateHeaderWithTimeInfo(parentTime uint64, numTx uint64, proposer []byte,
+	height uint64, blockHash, lastCommitHash []byte) {
 
 	es.mtx.Lock()
 	defer es.mtx.Unlock()
 
-	es.work.updateHeaderWithTimeInfo(config, parentTime, numTx)
+	es.work.updateHeaderWithTimeInfo(parentTime, numTx)
+	if err := linkHeader(es.work.header, height, blockHash, lastCommitHash); err != nil {
+		log.Error("Failed to link the header to the tendermint block", "err", err)
+	}
+
+	// The proposer authors the block, if it registered an ethereum address.
+	if coinbase, ok := es.rewardAddress(proposer); ok {
+		es.work.header.Coinbase = coinbase
+	}
+}
+
+// chainContext is the blockchain as seen by the EVM. Its engine is the pluto
+// engine, see installEngine.
+func (es *EthState) chainContext() core.ChainContext {
+	return es.ethereum.BlockChain()
+}
+
+// Engine returns the consensus engine of the chain
+// #unstable
+func (es *EthState) Engine() *Engine {
+	return es.engine
 }
 
 func (es *EthState) GasLimit() uint64 {
-	return es.work.gp.Gas()
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	return es.work.header.GasLimit
+}
+
+// Set the block size consensus params received in InitChain. They apply to
+// the block being built.
+func (es *EthState) SetBlockSize(blockSize BlockSize) error {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	es.maxBytes = blockSize.MaxBytes
+	if err := writeBlockSize(es.ethereum.ChainDb(), blockSize); err != nil {
+		return err
+	}
+	if blockSize.MaxGas > 0 {
+		es.work.header.GasLimit = uint64(blockSize.MaxGas)
+		es.work.gp = new(core.GasPool).AddGas(uint64(blockSize.MaxGas))
+	}
+	return nil
+}
+
+// Change the gas limit from the next block on and return the resulting
+// block size consensus params.
+func (es *EthState) UpdateMaxGas(maxGas int64) BlockSize {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	es.nextMaxGas = maxGas
+	return BlockSize{MaxBytes: es.maxBytes, MaxGas: maxGas}
+}
+
+// The block size consensus params of the block being built. Its gas limit
+// was inherited from the parent block or changed by UpdateMaxGas before.
+func (es *EthState) BlockSize() BlockSize {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	return BlockSize{MaxBytes: es.maxBytes, MaxGas: int64(es.work.header.GasLimit)}
 }
 
 //----------------------------------------------------------------------
@@ -166,28 +355,63 @@ type workState struct {
 	transactions []*ethTypes.Transaction
 	receipts     ethTypes.Receipts
 	allLogs      []*ethTypes.Log
+	rewards      []*Reward
+	fees         FeeSplit
+	votes        []abciTypes.VoteInfo // the last commit, weights the rewards
+	stakingLogs  []*ethTypes.Log      // staking events outside of any tx, see GetStakingLogs
 
 	totalUsedGas *uint64
 	gp           *core.GasPool
 }
 
-// nolint: unparam
-func (ws *workState) accumulateRewards(strategy *plutoTypes.Strategy) {
-	//ws.state.AddBalance(ws.header.Coinbase, ethash.FrontierBlockReward)
-	//todo:后续要获取到块的validators列表根据voting power按比例分配收益
+// Route the gas fees of the block, mint the block reward and split it together
+// with the validators' share of the fees between the validators of the last
+// commit by voting power. Minted and burned ether is accounted in the supply
+// counter.
+func (ws *workState) accumulateRewards(params *plutoTypes.Params,
+	resolve func([]byte) (common.Address, bool)) error {
+
 	ws.header.GasUsed = *ws.totalUsedGas
+
+	ws.fees = routeFees(ws.state, ws.collectedFees(), ws.header.Coinbase, params.Fees)
+	if err := ws.subSupply(ws.fees.Burned); err != nil {
+		return err
+	}
+
+	// the emission schedule decides the reward, the max supply caps it
+	reward, err := ws.mintable(params.BlockRewardAt(ws.header.Number.Uint64()), params.MaxSupply())
+	if err != nil {
+		return err
+	}
+	if err := ws.addSupply(reward); err != nil {
+		return err
+	}
+
+	total := new(big.Int).Add(reward, ws.fees.Validators)
+	ws.rewards = distributeRewards(ws.state, total, ws.header.Coinbase, ws.votes, resolve)
+	return nil
+}
+
+// collectedFees sums up the gas fees paid by the transactions of the block
+func (ws *workState) collectedFees() *big.Int {
+	fees := new(big.Int)
+	for i, tx := range ws.transactions {
+		fee := new(big.Int).SetUint64(ws.receipts[i].GasUsed)
+		fees.Add(fees, fee.Mul(fee, tx.GasPrice()))
+	}
+	return fees
 }
 
 // Runs ApplyTransaction against the ethereum blockchain, fetches any logs,
 // and appends the tx, receipt, and logs.
-func (ws *workState) deliverTx(blockchain *core.BlockChain, config *eth.Config,
+func (ws *workState) deliverTx(chain core.ChainContext, config *eth.Config,
 	chainConfig *params.ChainConfig, blockHash common.Hash,
 	tx *ethTypes.Transaction) abciTypes.ResponseDeliverTx {
 
 	ws.state.Prepare(tx.Hash(), blockHash, ws.txIndex)
 	receipt, _, err := core.ApplyTransaction(
 		chainConfig,
-		blockchain,
+		chain,
 		nil, // defaults to address of the author of the header
 		ws.gp,
 		ws.state,
@@ -212,10 +436,35 @@ func (ws *workState) deliverTx(blockchain *core.BlockChain, config *eth.Config,
 	return abciTypes.ResponseDeliverTx{Code: abciTypes.CodeTypeOK}
 }
 
-// Commit the ethereum state, update the header, make a new block and add it to
-// the ethereum blockchain. The application root hash is the hash of the
-// ethereum block.
-func (ws *workState) commit(blockchain *core.BlockChain, db ethdb.Database) (common.Hash, error) {
+// Hands a successful tx sent to the staking address to the staking keeper.
+// A rejected staking operation is refunded, but the tx stays in the block
+// with a failed receipt.
+func (ws *workState) deliverStakingTx(chainConfig *params.ChainConfig, keeper *staking.Keeper,
+	tx *ethTypes.Transaction) abciTypes.ResponseDeliverTx {
+
+	receipt := ws.receipts[len(ws.receipts)-1]
+	if tx.To() == nil || *tx.To() != staking.Address ||
+		receipt.Status != ethTypes.ReceiptStatusSuccessful {
+		return abciTypes.ResponseDeliverTx{Code: abciTypes.CodeTypeOK}
+	}
+
+	from, err := ethTypes.Sender(ethTypes.MakeSigner(chainConfig, ws.header.Number), tx)
+	if err != nil {
+		return abciTypes.ResponseDeliverTx{Code: errorCode, Log: err.Error()}
+	}
+	if err := keeper.DeliverTx(ws.state, ws.header.Number.Uint64(), from, tx); err != nil {
+		receipt.Status = ethTypes.ReceiptStatusFailed
+		return abciTypes.ResponseDeliverTx{Code: errorCode, Log: err.Error()}
+	}
+	return abciTypes.ResponseDeliverTx{Code: abciTypes.CodeTypeOK}
+}
+
+// Commit the ethereum state, update the header, make a new block, verify it
+// with the engine and add it to the ethereum blockchain together with the
+// receipts of the work. The application root hash is the state root, see
+// AppHash.
+func (ws *workState) commit(blockchain *core.BlockChain, engine *Engine,
+	processor *blockProcessor, db ethdb.Database) (common.Hash, error) {
 
 	// Commit ethereum state and update the header.
 	hashArray, err := ws.state.Commit(false) // XXX: ugh hardforks
@@ -224,36 +473,56 @@ func (ws *workState) commit(blockchain *core.BlockChain, db ethdb.Database) (com
 	}
 	ws.header.Root = hashArray
 
+	// Create block object. The state is committed, so the root stays the same.
+	block, err := engine.Finalize(blockchain, ws.header, ws.state, ws.transactions, nil, ws.receipts)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if block.Root() != hashArray {
+		return common.Hash{}, fmt.Errorf("state root of block %d changed from %x to %x",
+			block.NumberU64(), hashArray, block.Root())
+	}
+	if err := engine.VerifyHeader(blockchain, block.Header(), false); err != nil {
+		return common.Hash{}, err
+	}
 	for _, log := range ws.allLogs {
-		log.BlockHash = hashArray
+		log.BlockHash = block.Hash()
 	}
-
-	// Create block object and compute final commit hash (hash of the ethereum
-	// block).
-	block := ethTypes.NewBlock(ws.header, ws.transactions, nil, ws.receipts)
-	blockHash := block.Hash()
+	processor.expect(block, ws.receipts, ws.allLogs, *ws.totalUsedGas)
 
 	// Save the block to disk.
-	// log.Info("Committing block", "stateHash", hashArray, "blockHash", blockHash)
+	// log.Info("Committing block", "stateHash", hashArray, "blockHash", block.Hash())
 	_, err = blockchain.InsertChain([]*ethTypes.Block{block})
 	if err != nil {
 		// log.Info("Error inserting ethereum block in chain", "err", err)
 		return common.Hash{}, err
 	}
-	return blockHash, err
+	if err := writeBlockRewards(db, block.NumberU64(), ws.rewards); err != nil {
+		return common.Hash{}, err
+	}
+	if err := ws.writeFeeStats(db, block.NumberU64()); err != nil {
+		return common.Hash{}, err
+	}
+	if err := writeStakingLogs(db, block.NumberU64(), ws.stakingLogs); err != nil {
+		return common.Hash{}, err
+	}
+	return AppHash(block), err
 }
 
-func (ws *workState) updateHeaderWithTimeInfo(
-	config *params.ChainConfig, parentTime uint64, numTx uint64) {
-
-	lastBlock := ws.parent
-	parentHeader := &ethTypes.Header{
-		Difficulty: lastBlock.Difficulty(),
-		Number:     lastBlock.Number(),
-		Time:       lastBlock.Time(),
+// Store the fee split of the block together with the running totals.
+func (ws *workState) writeFeeStats(db ethdb.Database, number uint64) error {
+	parent, err := readFeeStats(db, number-1)
+	if err != nil {
+		return err
 	}
+	stats := &FeeStats{Block: ws.fees, Total: newFeeSplit()}
+	stats.Total.add(parent.Total)
+	stats.Total.add(ws.fees)
+	return writeFeeStats(db, number, stats)
+}
+
+func (ws *workState) updateHeaderWithTimeInfo(parentTime uint64, numTx uint64) {
 	ws.header.Time = new(big.Int).SetUint64(parentTime)
-	ws.header.Difficulty = ethash.CalcDifficulty(config, parentTime, parentHeader)
 	ws.transactions = make([]*ethTypes.Transaction, 0, numTx)
 	ws.receipts = make([]*ethTypes.Receipt, 0, numTx)
 	ws.allLogs = make([]*ethTypes.Log, 0, numTx)
@@ -261,12 +530,17 @@ func (ws *workState) updateHeaderWithTimeInfo(
 
 //----------------------------------------------------------------------
 
-// Create a new block header from the previous block.
-func newBlockHeader(receiver common.Address, prevBlock *ethTypes.Block) *ethTypes.Header {
+// Create a new block header from the previous block. The gas limit of the
+// previous block carries over unless gasLimit is set, so rebuilding a block
+// after a rollback gives it the limit it had.
+func newBlockHeader(receiver common.Address, prevBlock *ethTypes.Block, gasLimit uint64) *ethTypes.Header {
+	if gasLimit == 0 {
+		gasLimit = prevBlock.GasLimit()
+	}
 	return &ethTypes.Header{
 		Number:     prevBlock.Number().Add(prevBlock.Number(), big.NewInt(1)),
 		ParentHash: prevBlock.Hash(),
-		GasLimit:   core.CalcGasLimit(prevBlock, 0, 0),
+		GasLimit:   gasLimit,
 		Coinbase:   receiver,
 	}
 }
@@ -0,0 +1,78 @@
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+//----------------------------------------------------------------------
+// Gas fees are paid to the coinbase by core.ApplyTransaction. At the end of
+// the block they are routed from there according to the fee params.
+
+// FeeSplit tells where the gas fees went
+// #unstable
+type FeeSplit struct {
+	Collected  *big.Int // fees paid by the transactions
+	Burned     *big.Int
+	Treasury   *big.Int
+	Validators *big.Int // split between the validators by voting power
+	Coinbase   *big.Int // left with the coinbase
+}
+
+func newFeeSplit() FeeSplit {
+	return FeeSplit{
+		Collected:  new(big.Int),
+		Burned:     new(big.Int),
+		Treasury:   new(big.Int),
+		Validators: new(big.Int),
+		Coinbase:   new(big.Int),
+	}
+}
+
+// add adds the amounts of other to the split
+func (f FeeSplit) add(other FeeSplit) {
+	f.Collected.Add(f.Collected, other.Collected)
+	f.Burned.Add(f.Burned, other.Burned)
+	f.Treasury.Add(f.Treasury, other.Treasury)
+	f.Validators.Add(f.Validators, other.Validators)
+	f.Coinbase.Add(f.Coinbase, other.Coinbase)
+}
+
+// FeeStats are the fee splits of a block and of the chain up to that block
+// #unstable
+type FeeStats struct {
+	Block FeeSplit
+	Total FeeSplit
+}
+
+// percentOf returns percent of amount, rounded down
+func percentOf(amount *big.Int, percent uint64) *big.Int {
+	share := new(big.Int).Mul(amount, new(big.Int).SetUint64(percent))
+	return share.Div(share, big.NewInt(100))
+}
+
+// routeFees takes the burn, treasury and validator shares of the fees from
+// the coinbase. The validator share is returned in the split, to be
+// distributed together with the block reward.
+func routeFees(statedb *state.StateDB, fees *big.Int, coinbase common.Address,
+	params *plutoTypes.FeeParams) FeeSplit {
+
+	split := FeeSplit{
+		Collected:  new(big.Int).Set(fees),
+		Burned:     percentOf(fees, params.BurnPercent),
+		Treasury:   percentOf(fees, params.TreasuryPercent),
+		Validators: percentOf(fees, params.ValidatorPercent),
+	}
+	split.Coinbase = new(big.Int).Sub(fees, split.Burned)
+	split.Coinbase.Sub(split.Coinbase, split.Treasury)
+	split.Coinbase.Sub(split.Coinbase, split.Validators)
+
+	routed := new(big.Int).Sub(fees, split.Coinbase)
+	statedb.SubBalance(coinbase, routed)
+	statedb.AddBalance(params.Treasury, split.Treasury)
+	return split
+}
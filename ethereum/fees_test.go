@@ -0,0 +1,59 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+func newTestState(t *testing.T) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+func TestRouteFees(t *testing.T) {
+	coinbase := common.HexToAddress("0xc0")
+	params := &plutoTypes.FeeParams{
+		BurnPercent:      10,
+		TreasuryPercent:  20,
+		Treasury:         common.HexToAddress("0x7e"),
+		ValidatorPercent: 30,
+	}
+
+	// core.ApplyTransaction paid the fees to the coinbase
+	statedb := newTestState(t)
+	statedb.AddBalance(coinbase, big.NewInt(1005))
+
+	split := routeFees(statedb, big.NewInt(1005), coinbase, params)
+	expected := map[string]struct {
+		got  *big.Int
+		want int64
+	}{
+		"collected":  {split.Collected, 1005},
+		"burned":     {split.Burned, 100},
+		"treasury":   {split.Treasury, 201},
+		"validators": {split.Validators, 301},
+		"coinbase":   {split.Coinbase, 403},
+	}
+	for name, amount := range expected {
+		if amount.got.Int64() != amount.want {
+			t.Errorf("expected %s %d, got %v", name, amount.want, amount.got)
+		}
+	}
+
+	// the validator share stays to be distributed with the block reward
+	if balance := statedb.GetBalance(coinbase); balance.Int64() != 403 {
+		t.Errorf("expected the coinbase to keep 403, got %v", balance)
+	}
+	if balance := statedb.GetBalance(params.Treasury); balance.Int64() != 201 {
+		t.Errorf("expected the treasury to get 201, got %v", balance)
+	}
+}
@@ -0,0 +1,62 @@
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+//----------------------------------------------------------------------
+// There is no proof of work, so the difficulty of the blocks is constant.
+// Instead the headers link every ethereum block to the tendermint block it
+// was built from: the Extra field holds the tendermint height and block hash,
+// and the MixDigest is derived from the block hash and the last commit hash.
+// No EVM opcode reads the Extra field or the MixDigest, so the link is not
+// available to contracts, and exposing it on-chain, e.g. through a system
+// contract, is out of scope. Clients read it over json-rpc as the extraData
+// and mixHash of a block, the latter e.g. as a source of entropy that no
+// single validator controls in advance.
+
+// BlockDifficulty is the difficulty of every block
+// #unstable
+var BlockDifficulty = big.NewInt(1)
+
+// TendermintLink is the RLP encoded content of the Extra field of a header
+// #unstable
+type TendermintLink struct {
+	Height    uint64
+	BlockHash []byte
+}
+
+// HeaderLink decodes the tendermint link of a header
+// #unstable
+func HeaderLink(header *ethTypes.Header) (*TendermintLink, error) {
+	link := new(TendermintLink)
+	if err := rlp.DecodeBytes(header.Extra, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// MixDigest derives the mix digest of a header from the hash of its
+// tendermint block and the hash of the commit of the previous block
+// #unstable
+func MixDigest(blockHash, lastCommitHash []byte) common.Hash {
+	return crypto.Keccak256Hash(blockHash, lastCommitHash)
+}
+
+// linkHeader links a header to its tendermint block
+func linkHeader(header *ethTypes.Header, height uint64, blockHash, lastCommitHash []byte) error {
+	extra, err := rlp.EncodeToBytes(&TendermintLink{Height: height, BlockHash: blockHash})
+	if err != nil {
+		return err
+	}
+	header.Difficulty = new(big.Int).Set(BlockDifficulty)
+	header.Extra = extra
+	header.MixDigest = MixDigest(blockHash, lastCommitHash)
+	return nil
+}
@@ -0,0 +1,54 @@
+package ethereum
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLinkHeader(t *testing.T) {
+	tests := []struct {
+		name           string
+		height         uint64
+		blockHash      []byte
+		lastCommitHash []byte
+	}{
+		{"first block", 1, bytes.Repeat([]byte{1}, 20), nil},
+		{"later block", 1000, bytes.Repeat([]byte{2}, 20), bytes.Repeat([]byte{3}, 20)},
+		{"large height", 1 << 40, bytes.Repeat([]byte{4}, 32), bytes.Repeat([]byte{5}, 32)},
+	}
+	for _, test := range tests {
+		header := &ethTypes.Header{Number: new(big.Int).SetUint64(test.height), Difficulty: big.NewInt(1000)}
+		if err := linkHeader(header, test.height, test.blockHash, test.lastCommitHash); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		link, err := HeaderLink(header)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if link.Height != test.height || !bytes.Equal(link.BlockHash, test.blockHash) {
+			t.Errorf("%s: expected height %d and hash %x, got %d and %x",
+				test.name, test.height, test.blockHash, link.Height, link.BlockHash)
+		}
+		if mix := MixDigest(test.blockHash, test.lastCommitHash); header.MixDigest != mix {
+			t.Errorf("%s: expected mix digest %x, got %x", test.name, mix, header.MixDigest)
+		}
+		if header.Difficulty.Cmp(BlockDifficulty) != 0 {
+			t.Errorf("%s: expected difficulty %v, got %v", test.name, BlockDifficulty, header.Difficulty)
+		}
+	}
+
+	// the commit hash only goes into the mix digest
+	if MixDigest([]byte{1}, []byte{2}) == MixDigest([]byte{1}, []byte{3}) {
+		t.Error("expected the last commit hash to change the mix digest")
+	}
+
+	for _, extra := range [][]byte{nil, []byte("ethermint"), {0xc1, 0x01}} {
+		if _, err := HeaderLink(&ethTypes.Header{Extra: extra}); err == nil {
+			t.Errorf("expected extra %x to be no link", extra)
+		}
+	}
+}
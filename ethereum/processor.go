@@ -0,0 +1,123 @@
+package ethereum
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+//----------------------------------------------------------------------
+// The blocks are built by the work state. Besides running the txs it applies
+// the staking operations, the rewards and the fee routing, which
+// core.StateProcessor knows nothing about, and it marks rejected staking
+// operations as failed in their receipts. InsertChain processes a block again
+// before storing it, so blockProcessor hands it the receipts and logs of the
+// work state instead. The chain then stores what the block actually did.
+
+// blockProcessor implements core.Processor
+type blockProcessor struct {
+	mtx      sync.Mutex
+	hash     common.Hash // the block about to be inserted
+	receipts ethTypes.Receipts
+	logs     []*ethTypes.Log
+	usedGas  uint64
+
+	// processes any other block, e.g. for tracing
+	fallback core.Processor
+}
+
+func newBlockProcessor(fallback core.Processor) *blockProcessor {
+	return &blockProcessor{fallback: fallback}
+}
+
+// expect sets the results of the block about to be inserted
+func (p *blockProcessor) expect(block *ethTypes.Block, receipts ethTypes.Receipts,
+	logs []*ethTypes.Log, usedGas uint64) {
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.hash = block.Hash()
+	p.receipts = receipts
+	p.logs = logs
+	p.usedGas = usedGas
+}
+
+// Process returns the results of the work state for the expected block, once.
+// Any other block is executed by the fallback processor.
+func (p *blockProcessor) Process(block *ethTypes.Block, statedb *state.StateDB,
+	cfg vm.Config) (ethTypes.Receipts, []*ethTypes.Log, uint64, error) {
+
+	p.mtx.Lock()
+	if block.Hash() == p.hash {
+		receipts, logs, usedGas := p.receipts, p.logs, p.usedGas
+		p.hash, p.receipts, p.logs = common.Hash{}, nil, nil
+		p.mtx.Unlock()
+		return receipts, logs, usedGas, nil
+	}
+	p.mtx.Unlock()
+
+	return p.fallback.Process(block, statedb, cfg)
+}
+
+//----------------------------------------------------------------------
+
+// blockValidator implements core.Validator with the pluto engine. It checks
+// what the work state and blockProcessor agree on: the body of the block and
+// its receipts. The state root isn't checked, the state of an expected block
+// was committed by the work state and isn't processed again.
+type blockValidator struct {
+	chain  *core.BlockChain
+	engine *Engine
+}
+
+func newBlockValidator(chain *core.BlockChain, engine *Engine) *blockValidator {
+	return &blockValidator{chain: chain, engine: engine}
+}
+
+// ValidateBody checks the uncles and the transactions of a block against its
+// header, and that it can be processed on top of its parent
+func (v *blockValidator) ValidateBody(block *ethTypes.Block) error {
+	if v.chain.HasBlockAndState(block.Hash(), block.NumberU64()) {
+		return core.ErrKnownBlock
+	}
+	if err := v.engine.VerifyUncles(v.chain, block); err != nil {
+		return err
+	}
+	if hash := ethTypes.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, block.UncleHash())
+	}
+	if hash := ethTypes.DeriveSha(block.Transactions()); hash != block.TxHash() {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, block.TxHash())
+	}
+	if !v.chain.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
+		if !v.chain.HasBlock(block.ParentHash(), block.NumberU64()-1) {
+			return consensus.ErrUnknownAncestor
+		}
+		return consensus.ErrPrunedAncestor
+	}
+	return nil
+}
+
+// ValidateState checks the gas used and the receipts of a processed block
+// against its header
+func (v *blockValidator) ValidateState(block, parent *ethTypes.Block, statedb *state.StateDB,
+	receipts ethTypes.Receipts, usedGas uint64) error {
+
+	if block.GasUsed() != usedGas {
+		return fmt.Errorf("invalid gas used (remote: %d local: %d)", block.GasUsed(), usedGas)
+	}
+	if bloom := ethTypes.CreateBloom(receipts); bloom != block.Bloom() {
+		return fmt.Errorf("invalid bloom (remote: %x  local: %x)", block.Bloom(), bloom)
+	}
+	if hash := ethTypes.DeriveSha(receipts); hash != block.ReceiptHash() {
+		return fmt.Errorf("invalid receipt root hash (remote: %x local: %x)", block.ReceiptHash(), hash)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/zhuzeyu/pluto/staking"
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+//----------------------------------------------------------------------
+// Block rewards are split between the validators pro rata to their voting power
+
+// Reward is an amount credited to an address at the end of a block
+// #unstable
+type Reward struct {
+	Address common.Address
+	Amount  *big.Int
+}
+
+// rewardAddress returns the address that authors the blocks and collects the
+// rewards of the validator with the given tendermint address: the address in
+// the registry or else the one configured in the genesis file.
+func rewardAddress(statedb *state.StateDB, params *plutoTypes.Params, keeper *staking.Keeper,
+	validator []byte) (common.Address, bool) {
+
+	if address, ok := keeper.RegisteredAddress(statedb, validator); ok {
+		return address, true
+	}
+	return params.RewardAddress(validator)
+}
+
+// distributeRewards credits total to the reward addresses of the validators
+// of the last commit, weighted by their voting power. The votes are consensus
+// data, so every node splits the same way, also after a restart. Validators
+// without a reward address are skipped, and whatever can't be split
+// (rounding, unknown validators) goes to the coinbase.
+func distributeRewards(statedb *state.StateDB, total *big.Int, coinbase common.Address,
+	votes []abciTypes.VoteInfo, resolve func([]byte) (common.Address, bool)) []*Reward {
+
+	if total.Sign() == 0 {
+		return nil
+	}
+
+	type share struct {
+		address common.Address
+		power   int64
+	}
+	shares := make([]share, 0, len(votes))
+	totalPower := new(big.Int)
+	for _, vote := range votes {
+		validator := vote.Validator
+		if validator.Power <= 0 {
+			continue
+		}
+		address, ok := resolve(validator.Address)
+		if !ok {
+			continue
+		}
+		shares = append(shares, share{address, validator.Power})
+		totalPower.Add(totalPower, big.NewInt(validator.Power))
+	}
+
+	rewards := make([]*Reward, 0, len(shares)+1)
+	remainder := new(big.Int).Set(total)
+	for _, s := range shares {
+		amount := new(big.Int).Mul(total, big.NewInt(s.power))
+		amount.Div(amount, totalPower)
+		if amount.Sign() == 0 {
+			continue
+		}
+		statedb.AddBalance(s.address, amount)
+		remainder.Sub(remainder, amount)
+		rewards = append(rewards, &Reward{Address: s.address, Amount: amount})
+	}
+	if remainder.Sign() > 0 {
+		statedb.AddBalance(coinbase, remainder)
+		rewards = append(rewards, &Reward{Address: coinbase, Amount: remainder})
+	}
+	return rewards
+}
@@ -0,0 +1,72 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+func vote(address string, power int64) abciTypes.VoteInfo {
+	return abciTypes.VoteInfo{
+		Validator:       abciTypes.Validator{Address: []byte(address), Power: power},
+		SignedLastBlock: true,
+	}
+}
+
+func TestDistributeRewards(t *testing.T) {
+	coinbase := common.HexToAddress("0xc0")
+	addresses := map[string]common.Address{
+		"a": common.HexToAddress("0x0a"),
+		"b": common.HexToAddress("0x0b"),
+		"z": common.HexToAddress("0x0f"),
+	}
+	resolve := func(validator []byte) (common.Address, bool) {
+		address, ok := addresses[string(validator)]
+		return address, ok
+	}
+
+	statedb := newTestState(t)
+	votes := []abciTypes.VoteInfo{
+		vote("a", 1),
+		vote("b", 2),
+		vote("c", 3), // no reward address
+		vote("z", 0), // no voting power
+	}
+	rewards := distributeRewards(statedb, big.NewInt(100), coinbase, votes, resolve)
+
+	expected := map[common.Address]int64{
+		addresses["a"]: 33,
+		addresses["b"]: 66,
+		coinbase:       1, // rounding
+		addresses["z"]: 0,
+	}
+	for address, amount := range expected {
+		if balance := statedb.GetBalance(address); balance.Int64() != amount {
+			t.Errorf("expected %x to get %d, got %v", address, amount, balance)
+		}
+	}
+	if len(rewards) != 3 {
+		t.Errorf("expected 3 rewards, got %d", len(rewards))
+	}
+}
+
+func TestDistributeRewardsWithoutVotes(t *testing.T) {
+	coinbase := common.HexToAddress("0xc0")
+	resolve := func([]byte) (common.Address, bool) { return common.Address{}, false }
+
+	statedb := newTestState(t)
+	if rewards := distributeRewards(statedb, new(big.Int), coinbase, nil, resolve); rewards != nil {
+		t.Errorf("expected no rewards, got %v", rewards)
+	}
+
+	rewards := distributeRewards(statedb, big.NewInt(100), coinbase, nil, resolve)
+	if len(rewards) != 1 || rewards[0].Address != coinbase {
+		t.Errorf("expected the coinbase to get everything, got %v", rewards)
+	}
+	if balance := statedb.GetBalance(coinbase); balance.Int64() != 100 {
+		t.Errorf("expected the coinbase to get 100, got %v", balance)
+	}
+}
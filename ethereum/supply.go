@@ -0,0 +1,141 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+//----------------------------------------------------------------------
+// The total supply of ether is kept in the storage of a reserved system
+// address, so it is part of the state root. `pluto init` seeds the counter
+// from the genesis alloc and every mint and burn keeps it up to date, so it
+// never has to be counted from the state. Chains initialised without it do
+// not track the supply and cannot have a max supply.
+
+// SupplyAddress is the reserved system address that holds the supply counter
+// #unstable
+var SupplyAddress = common.HexToAddress("0x0000000000000000000000000000000000001001")
+
+var (
+	supplyKey            = crypto.Keccak256Hash([]byte("supply"))
+	supplyInitializedKey = crypto.Keccak256Hash([]byte("supply.initialized"))
+)
+
+// ErrSupplyUnknown is returned for states of chains without a supply counter
+var ErrSupplyUnknown = errors.New("total supply not tracked on this chain")
+
+// TotalSupply returns the total supply of ether in the given state. It fails
+// with ErrSupplyUnknown if the chain has no supply counter.
+// #unstable
+func TotalSupply(statedb *state.StateDB) (*big.Int, error) {
+	if !supplyTracked(statedb) {
+		return nil, ErrSupplyUnknown
+	}
+	return supply(statedb), nil
+}
+
+// supply reads the supply counter
+func supply(statedb *state.StateDB) *big.Int {
+	return statedb.GetState(SupplyAddress, supplyKey).Big()
+}
+
+// SeedSupply adds the supply counter to the genesis alloc, seeded with the sum
+// of its balances
+// #unstable
+func SeedSupply(genesis *core.Genesis) error {
+	if _, ok := genesis.Alloc[SupplyAddress]; ok {
+		return fmt.Errorf("genesis alloc must not contain the supply address %x", SupplyAddress)
+	}
+	total := new(big.Int)
+	for _, account := range genesis.Alloc {
+		if account.Balance != nil {
+			total.Add(total, account.Balance)
+		}
+	}
+	if genesis.Alloc == nil {
+		genesis.Alloc = make(core.GenesisAlloc)
+	}
+	// A nonce keeps the account from being removed as empty (EIP-161).
+	genesis.Alloc[SupplyAddress] = core.GenesisAccount{
+		Balance: new(big.Int),
+		Nonce:   1,
+		Storage: map[common.Hash]common.Hash{
+			supplyKey:            common.BigToHash(total),
+			supplyInitializedKey: common.BigToHash(big.NewInt(1)),
+		},
+	}
+	return nil
+}
+
+// supplyTracked reports whether the state has a supply counter
+func supplyTracked(statedb *state.StateDB) bool {
+	return statedb.GetState(SupplyAddress, supplyInitializedKey) != (common.Hash{})
+}
+
+// checkSupply fails if the chain has a max supply but no supply counter in
+// its genesis state to enforce it with
+func checkSupply(blockchain *core.BlockChain, params *plutoTypes.Params) error {
+	if params.MaxSupply() == nil {
+		return nil
+	}
+	statedb, err := blockchain.StateAt(blockchain.Genesis().Root())
+	if err != nil {
+		return err
+	}
+	if !supplyTracked(statedb) {
+		return errors.New("max supply set, but the chain was initialised without a supply counter")
+	}
+	return nil
+}
+
+func (ws *workState) setSupply(supply *big.Int) {
+	// A nonce keeps the account from being removed as empty (EIP-161).
+	if ws.state.GetNonce(SupplyAddress) == 0 {
+		ws.state.SetNonce(SupplyAddress, 1)
+	}
+	ws.state.SetState(SupplyAddress, supplyKey, common.BigToHash(supply))
+}
+
+// mintable caps amount so that minting it does not exceed maxSupply
+func (ws *workState) mintable(amount, maxSupply *big.Int) (*big.Int, error) {
+	if maxSupply == nil {
+		return amount, nil
+	}
+	if !supplyTracked(ws.state) {
+		return nil, ErrSupplyUnknown
+	}
+	room := new(big.Int).Sub(maxSupply, supply(ws.state))
+	if room.Sign() <= 0 {
+		return new(big.Int), nil
+	}
+	if amount.Cmp(room) > 0 {
+		return room, nil
+	}
+	return amount, nil
+}
+
+// addSupply records newly minted ether
+func (ws *workState) addSupply(amount *big.Int) error {
+	if !supplyTracked(ws.state) {
+		return nil
+	}
+	ws.setSupply(new(big.Int).Add(supply(ws.state), amount))
+	return nil
+}
+
+// subSupply records burned ether
+func (ws *workState) subSupply(amount *big.Int) error {
+	if !supplyTracked(ws.state) {
+		return nil
+	}
+	ws.setSupply(new(big.Int).Sub(supply(ws.state), amount))
+	return nil
+}
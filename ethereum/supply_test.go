@@ -0,0 +1,71 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestSeedSupply(t *testing.T) {
+	tests := []struct {
+		name   string
+		alloc  core.GenesisAlloc
+		supply int64
+		err    bool
+	}{
+		{"no alloc", nil, 0, false},
+		{"balances", core.GenesisAlloc{
+			common.HexToAddress("0x01"): {Balance: big.NewInt(100)},
+			common.HexToAddress("0x02"): {Balance: big.NewInt(23)},
+			common.HexToAddress("0x03"): {Code: []byte{0}},
+		}, 123, false},
+		{"supply address", core.GenesisAlloc{
+			SupplyAddress: {Balance: big.NewInt(1)},
+		}, 0, true},
+	}
+	for _, test := range tests {
+		genesis := &core.Genesis{Alloc: test.alloc}
+		if err := SeedSupply(genesis); (err != nil) != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+
+		db := ethdb.NewMemDatabase()
+		statedb, err := state.New(genesis.ToBlock(db).Root(), state.NewDatabase(db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if supply, err := TotalSupply(statedb); err != nil || supply.Cmp(big.NewInt(test.supply)) != 0 {
+			t.Errorf("%s: expected a supply of %d, got %v (%v)", test.name, test.supply, supply, err)
+		}
+	}
+}
+
+func TestUntrackedSupply(t *testing.T) {
+	ws := &workState{state: newTestState(t)}
+	if err := ws.addSupply(big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.subSupply(big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+	if ws.state.Exist(SupplyAddress) {
+		t.Error("expected no supply counter to be created")
+	}
+	if _, err := TotalSupply(ws.state); err != ErrSupplyUnknown {
+		t.Errorf("expected %v, got %v", ErrSupplyUnknown, err)
+	}
+	if amount, err := ws.mintable(big.NewInt(10), nil); err != nil || amount.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("expected the whole amount without a max supply, got %v (%v)", amount, err)
+	}
+	if _, err := ws.mintable(big.NewInt(10), big.NewInt(100)); err != ErrSupplyUnknown {
+		t.Errorf("expected %v with a max supply, got %v", ErrSupplyUnknown, err)
+	}
+}
@@ -1,7 +1,6 @@
 package ethereum
 
 import (
-	"bytes"
 	"fmt"
 	"time"
 
@@ -53,23 +52,53 @@ func (b *Backend) BroadcastTxSync(tx tmTypes.Tx) (*ctypes.ResultBroadcastTx, err
 	}, nil
 }
 
-// BroadcastTx broadcasts a transaction to tendermint core
+// ResubmitTx hands a transaction to the tendermint mempool without waiting for
+// the result, so it can be used while the mempool is locked, e.g. from CheckTx.
+// Without a local mempool it is broadcast over the rpc.
 // #unstable
-func (b *Backend) BroadcastTx(txs []*ethTypes.Transaction) error {
-	var result interface{}
+func (b *Backend) ResubmitTx(tx *ethTypes.Transaction) {
+	go func() {
+		if b.memPool == nil {
+			if err := b.BroadcastTx([]*ethTypes.Transaction{tx}); err != nil {
+				log.Error("Resubmit error", "hash", tx.Hash(), "err", err)
+			}
+			return
+		}
+		data, err := EncodeTxs([]*ethTypes.Transaction{tx})
+		if err != nil {
+			log.Error("Resubmit error", "hash", tx.Hash(), "err", err)
+			return
+		}
+		if err := b.memPool.CheckTx(data, nil); err != nil {
+			log.Error("Resubmit error", "hash", tx.Hash(), "err", err)
+		}
+	}()
+}
 
-	buf := new(bytes.Buffer)
+// BroadcastTx broadcasts transactions to tendermint core one by one, so the
+// mempool accepts or rejects every tx on its own. Txs that are not accepted
+// are logged.
+// #unstable
+func (b *Backend) BroadcastTx(txs []*ethTypes.Transaction) error {
 	for _, tx := range txs {
-		if err := tx.EncodeRLP(buf); err != nil {
+		data, err := EncodeTxs([]*ethTypes.Transaction{tx})
+		if err != nil {
 			return err
 		}
-	}
-	params := map[string]interface{}{
-		"tx": buf.Bytes(),
-	}
+		params := map[string]interface{}{
+			"tx": data,
+		}
 
-	_, err := b.client.Call("broadcast_tx_sync", params, &result)
-	return err
+		result := new(ctypes.ResultBroadcastTx)
+		if _, err := b.client.Call("broadcast_tx_sync", params, result); err != nil {
+			return err
+		}
+		if result.Code != abciTypes.CodeTypeOK {
+			log.Info("Tx not added to the mempool", "hash", tx.Hash(),
+				"code", result.Code, "log", result.Log)
+		}
+	}
+	return nil
 }
 
 //----------------------------------------------------------------------
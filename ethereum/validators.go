@@ -0,0 +1,126 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+//----------------------------------------------------------------------
+// The validator set can be governed by a contract instead of the staking
+// module. At the end of every block its getValidators function is called
+// against the work state, so changes made by the transactions of the block
+// take effect right away.
+
+// validatorCallGas caps the gas of a getValidators call
+const validatorCallGas = 50000000
+
+// maxValidatorPower keeps the total voting power within the tendermint limit
+const maxValidatorPower = math.MaxInt64 / 8
+
+var (
+	validatorContractABI abi.ABI
+
+	errNoValidatorContract = errors.New("no validator contract configured")
+)
+
+func init() {
+	var err error
+	validatorContractABI, err = abi.JSON(strings.NewReader(plutoTypes.ValidatorContractABI))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ContractValidators returns the validator set held by the validator contract
+// in the latest working state.
+func (es *EthState) ContractValidators() ([]abciTypes.ValidatorUpdate, error) {
+	es.mtx.Lock()
+	defer es.mtx.Unlock()
+
+	if es.params.ValidatorContract == nil {
+		return nil, errNoValidatorContract
+	}
+	return es.work.contractValidators(es.chainContext(),
+		es.ethereum.APIBackend.ChainConfig(), *es.params.ValidatorContract)
+}
+
+// contractValidators calls getValidators on a copy of the state, so that the
+// call leaves no trace in the block.
+func (ws *workState) contractValidators(chain core.ChainContext,
+	chainConfig *params.ChainConfig, contract common.Address) ([]abciTypes.ValidatorUpdate, error) {
+
+	input, err := validatorContractABI.Pack("getValidators")
+	if err != nil {
+		return nil, err
+	}
+
+	from := common.Address{}
+	msg := ethTypes.NewMessage(from, &contract, 0, new(big.Int), validatorCallGas,
+		new(big.Int), input, false)
+	context := core.NewEVMContext(msg, ws.header, chain, nil)
+	evm := vm.NewEVM(context, ws.state.Copy(), chainConfig, vm.Config{})
+	output, _, err := evm.StaticCall(vm.AccountRef(from), contract, input, validatorCallGas)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		PubKeys [][32]byte
+		Powers  []*big.Int
+	}
+	if err := validatorContractABI.Unpack(&result, "getValidators", output); err != nil {
+		return nil, err
+	}
+	return contractValidatorUpdates(result.PubKeys, result.Powers)
+}
+
+// contractValidatorUpdates checks the set returned by the contract. Entries
+// with zero power are left out.
+func contractValidatorUpdates(pubKeys [][32]byte, powers []*big.Int) ([]abciTypes.ValidatorUpdate, error) {
+	if len(pubKeys) != len(powers) {
+		return nil, fmt.Errorf("validator contract returned %d keys and %d powers",
+			len(pubKeys), len(powers))
+	}
+
+	seen := make(map[[32]byte]bool, len(pubKeys))
+	validators := make([]abciTypes.ValidatorUpdate, 0, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		if seen[pubKey] {
+			return nil, fmt.Errorf("validator contract returned key %x twice", pubKey)
+		}
+		seen[pubKey] = true
+		if powers[i].Sign() == 0 {
+			continue
+		}
+		if !powers[i].IsInt64() || powers[i].Int64() > maxValidatorPower {
+			return nil, fmt.Errorf("validator contract returned invalid power %v for key %x",
+				powers[i], pubKey)
+		}
+		validators = append(validators, abciTypes.ValidatorUpdate{
+			PubKey: abciTypes.PubKey{
+				Type: tmTypes.ABCIPubKeyTypeEd25519,
+				Data: common.CopyBytes(pubKey[:]),
+			},
+			Power: powers[i].Int64(),
+		})
+	}
+	if len(validators) == 0 {
+		return nil, errors.New("validator contract returned an empty validator set")
+	}
+	return validators, nil
+}
@@ -0,0 +1,135 @@
+package staking
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+var (
+	errNotJailed  = errors.New("staking: validator is not jailed")
+	errTombstoned = errors.New("staking: validator was jailed for double signing")
+	errJailTime   = errors.New("staking: jail time is not over yet")
+)
+
+// Jail is the outcome of jailing a validator for downtime
+// #unstable
+type Jail struct {
+	PubKey common.Hash
+	Until  uint64 // height from which the validator can unjail
+}
+
+// Address returns the tendermint address of the jailed validator
+func (j *Jail) Address() []byte {
+	return tmAddress(j.PubKey)
+}
+
+// InitChain registers the genesis validators with their genesis power. Their
+// owner is the reward address configured for them in the genesis file, if any,
+// which also seeds the registry. Validators without one have no owner and
+// are managed with signatures of their validator key.
+// #unstable
+func (k *Keeper) InitChain(statedb *state.StateDB, validators []abciTypes.ValidatorUpdate,
+	params *plutoTypes.Params) {
+
+	s := store{statedb}
+	for _, validator := range validators {
+		if validator.PubKey.Type != tmTypes.ABCIPubKeyTypeEd25519 ||
+			len(validator.PubKey.Data) != common.HashLength {
+			continue
+		}
+		pubKey := common.BytesToHash(validator.PubKey.Data)
+		if s.getValidator(pubKey) != nil {
+			continue
+		}
+
+		owner, ok := params.RewardAddress(tmAddress(pubKey))
+		v := newValidator(pubKey, owner)
+		v.GenesisPower = validator.Power
+		v.Power = validator.Power
+		s.addValidator(pubKey)
+		s.setValidator(v)
+		if ok {
+			s.setRegistered(tmAddress(pubKey), owner)
+		}
+	}
+}
+
+// HandleVotes records which validators signed the last block and jails those
+// that missed more than the allowed share of the signing window.
+// #unstable
+func (k *Keeper) HandleVotes(statedb *state.StateDB, height uint64,
+	votes []abciTypes.VoteInfo) []*Jail {
+
+	if k.contract {
+		return nil
+	}
+	s := store{statedb}
+	window := k.slashing.SignedBlocksWindow
+	maxMissed := window * k.slashing.MaxMissedPercent / 100
+
+	var jails []*Jail
+	for _, vote := range votes {
+		pubKey, ok := s.pubKeyOf(vote.Validator.Address)
+		if !ok {
+			continue
+		}
+		v := s.getValidator(pubKey)
+		if v == nil || v.Jailed {
+			continue
+		}
+
+		index := v.SignCount % window
+		missed := !vote.SignedLastBlock
+		if missed != s.getMissed(v, index) {
+			if missed {
+				v.Missed++
+			} else {
+				v.Missed--
+			}
+			s.setMissed(v, index, missed)
+		}
+		v.SignCount++
+
+		if v.SignCount >= window && v.Missed > maxMissed {
+			v.Jailed = true
+			v.JailedUntil = height + k.slashing.DowntimeJailDuration
+			v.SignCount = 0
+			v.Missed = 0
+			v.Epoch++
+			jails = append(jails, &Jail{PubKey: pubKey, Until: v.JailedUntil})
+		}
+		s.setValidator(v)
+	}
+	return jails
+}
+
+// unjail handles OpUnjail, which may carry a signature of the validator key
+// after the public key
+func (k *Keeper) unjail(s store, height uint64, from common.Address, pubKey common.Hash,
+	signature []byte) error {
+
+	v := s.getValidator(pubKey)
+	if v == nil || !v.Jailed {
+		return errNotJailed
+	}
+	if err := authorize(v, from, signature); err != nil {
+		return err
+	}
+	if v.Tombstoned {
+		return errTombstoned
+	}
+	if height < v.JailedUntil {
+		return errJailTime
+	}
+
+	v.Jailed = false
+	s.setValidator(v)
+	return nil
+}
@@ -0,0 +1,151 @@
+package staking
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+// newLivenessKeeper registers testPubKey as genesis validator with a signing
+// window of 4 blocks, of which 2 may be missed, and 10 blocks of jail time.
+// With owned, testOwner is its reward address in the genesis file.
+func newLivenessKeeper(t *testing.T, owned bool) (*Keeper, *state.StateDB) {
+	params := plutoTypes.DefaultParams()
+	params.Slashing.SignedBlocksWindow = 4
+	params.Slashing.MaxMissedPercent = 50
+	params.Slashing.DowntimeJailDuration = 10
+	if owned {
+		params.Validators[fmt.Sprintf("%X", tmAddress(testPubKey))] = testOwner
+	}
+	if err := params.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	k := NewKeeper(params)
+	statedb := newTestState(t)
+	k.InitChain(statedb, []abciTypes.ValidatorUpdate{{
+		PubKey: abciTypes.PubKey{Type: tmTypes.ABCIPubKeyTypeEd25519, Data: testPubKey[:]},
+		Power:  10,
+	}}, params)
+	return k, statedb
+}
+
+// handleVotes feeds the votes of testPubKey from height 1 on and returns the
+// height at which it got jailed, or zero
+func handleVotes(k *Keeper, statedb *state.StateDB, signed []bool) uint64 {
+	for i, signedLastBlock := range signed {
+		height := uint64(i + 1)
+		jails := k.HandleVotes(statedb, height, []abciTypes.VoteInfo{{
+			Validator:       abciTypes.Validator{Address: tmAddress(testPubKey), Power: 10},
+			SignedLastBlock: signedLastBlock,
+		}})
+		if len(jails) != 0 {
+			return height
+		}
+	}
+	return 0
+}
+
+func TestHandleVotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		signed []bool
+		jailed uint64
+	}{
+		{"all signed", []bool{true, true, true, true, true, true}, 0},
+		{"missed half the window", []bool{false, true, false, true, true, true}, 0},
+		{"missed before the window is full", []bool{false, false, false}, 0},
+		{"missed too many", []bool{false, false, true, false}, 4},
+		{"missed too many later", []bool{true, true, true, true, false, false, false}, 7},
+	}
+	for _, test := range tests {
+		k, statedb := newLivenessKeeper(t, true)
+		if jailed := handleVotes(k, statedb, test.signed); jailed != test.jailed {
+			t.Errorf("%s: expected to be jailed at %d, got %d", test.name, test.jailed, jailed)
+			continue
+		}
+
+		v := store{statedb}.getValidator(testPubKey)
+		if v.Jailed != (test.jailed != 0) {
+			t.Errorf("%s: expected jailed %v", test.name, test.jailed != 0)
+		}
+		if test.jailed != 0 && v.JailedUntil != test.jailed+10 {
+			t.Errorf("%s: expected to be jailed until %d, got %d", test.name, test.jailed+10, v.JailedUntil)
+		}
+		power := int64(10)
+		if test.jailed != 0 {
+			power = 0
+		}
+		if updates := k.EndBlock(statedb, uint64(len(test.signed))); (len(updates) != 0) != (test.jailed != 0) ||
+			(len(updates) != 0 && updates[0].Power != power) {
+			t.Errorf("%s: expected power %d, got updates %v", test.name, power, updates)
+		}
+	}
+}
+
+func TestUnjail(t *testing.T) {
+	other := common.HexToAddress("0x0b")
+	otherKey := ed25519.GenPrivKeyFromSecret([]byte("other"))
+
+	tests := []struct {
+		name       string
+		owned      bool
+		tombstoned bool
+		jailed     bool
+		height     uint64
+		from       common.Address
+		signature  func() []byte
+		err        error
+	}{
+		{"owner after jail time", true, false, true, 14, testOwner, nil, nil},
+		{"owner before jail time", true, false, true, 13, testOwner, nil, errJailTime},
+		{"not jailed", true, false, false, 14, testOwner, nil, errNotJailed},
+		{"tombstoned", true, true, true, 14, testOwner, nil, errTombstoned},
+		{"other sender", true, false, true, 14, other, nil, errNotOwner},
+		{"no owner", false, false, true, 14, testOwner, nil, errNotOwner},
+		{"no owner, signed by the validator key", false, false, true, 14, other,
+			func() []byte { return sign(t, testKey, other) }, nil},
+		{"owned, signed by the validator key", true, false, true, 14, other,
+			func() []byte { return sign(t, testKey, other) }, nil},
+		{"signed for another sender", false, false, true, 14, other,
+			func() []byte { return sign(t, testKey, testOwner) }, errSignature},
+		{"signed by another key", false, false, true, 14, other,
+			func() []byte { return sign(t, otherKey, other) }, errSignature},
+	}
+	for _, test := range tests {
+		k, statedb := newLivenessKeeper(t, test.owned)
+		if test.jailed && handleVotes(k, statedb, []bool{false, false, false, false}) != 4 {
+			t.Fatalf("%s: expected to be jailed at 4", test.name)
+		}
+		if test.tombstoned && k.SlashDoubleSign(statedb, tmAddress(testPubKey)) == nil {
+			t.Fatalf("%s: expected to be tombstoned", test.name)
+		}
+		k.EndBlock(statedb, 4)
+
+		data := append([]byte{OpUnjail}, testPubKey[:]...)
+		if test.signature != nil {
+			data = append(data, test.signature()...)
+		}
+		if err := deliverData(k, statedb, test.height, test.from, ether(0), data); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if test.err != nil || !test.jailed {
+			continue
+		}
+		if v := (store{statedb}).getValidator(testPubKey); v.Jailed {
+			t.Errorf("%s: expected to be unjailed", test.name)
+		}
+		if updates := k.EndBlock(statedb, test.height); len(updates) != 1 || updates[0].Power != 10 {
+			t.Errorf("%s: expected the genesis power back, got %v", test.name, updates)
+		}
+	}
+}
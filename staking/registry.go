@@ -0,0 +1,64 @@
+package staking
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//----------------------------------------------------------------------
+// The registry links tendermint validator addresses to the ethereum
+// addresses that author their blocks and collect their rewards. It is seeded
+// from the genesis file and with the owner of every new stake, and the owner
+// of a validator, or whoever holds its key, can point it to another address
+// with OpSetAddress.
+
+var registryKeyPrefix = []byte("registry")
+
+var errInvalidAddress = errors.New("staking: invalid reward address")
+
+func registryKey(address []byte) common.Hash {
+	return crypto.Keccak256Hash(registryKeyPrefix, address)
+}
+
+// getRegistered returns the ethereum address registered for a validator
+func (s store) getRegistered(address []byte) (common.Address, bool) {
+	value := s.get(registryKey(address))
+	if value == (common.Hash{}) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(value[:]), true
+}
+
+// setRegistered registers the ethereum address of a validator
+func (s store) setRegistered(address []byte, ethAddress common.Address) {
+	s.set(registryKey(address), common.BytesToHash(ethAddress[:]))
+}
+
+// RegisteredAddress returns the ethereum address registered for the validator
+// with the given tendermint address
+// #unstable
+func (k *Keeper) RegisteredAddress(statedb *state.StateDB, address []byte) (common.Address, bool) {
+	return store{statedb}.getRegistered(address)
+}
+
+// setAddress handles OpSetAddress, which carries the new address after the
+// public key, optionally followed by a signature of the validator key
+func (k *Keeper) setAddress(s store, from common.Address, pubKey common.Hash,
+	args []byte) error {
+
+	if len(args) != common.AddressLength && len(args) != common.AddressLength+signatureLength {
+		return errInvalidAddress
+	}
+	v := s.getValidator(pubKey)
+	if v == nil {
+		return errNotRegistered
+	}
+	if err := authorize(v, from, args[common.AddressLength:]); err != nil {
+		return err
+	}
+	s.setRegistered(tmAddress(pubKey), common.BytesToAddress(args[:common.AddressLength]))
+	return nil
+}
@@ -0,0 +1,55 @@
+package staking
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSetAddress(t *testing.T) {
+	other := common.HexToAddress("0x0b")
+	coinbase := common.HexToAddress("0xc0")
+	setAddress := func(pubKey common.Hash, args ...[]byte) []byte {
+		data := append([]byte{OpSetAddress}, pubKey[:]...)
+		for _, arg := range args {
+			data = append(data, arg...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name  string
+		owned bool
+		from  common.Address
+		data  func() []byte
+		err   error
+	}{
+		{"owner", true, testOwner,
+			func() []byte { return setAddress(testPubKey, coinbase[:]) }, nil},
+		{"other sender", true, other,
+			func() []byte { return setAddress(testPubKey, coinbase[:]) }, errNotOwner},
+		{"no owner", false, testOwner,
+			func() []byte { return setAddress(testPubKey, coinbase[:]) }, errNotOwner},
+		{"no owner, signed by the validator key", false, other,
+			func() []byte { return setAddress(testPubKey, coinbase[:], sign(t, testKey, other)) }, nil},
+		{"signed for another sender", false, other,
+			func() []byte { return setAddress(testPubKey, coinbase[:], sign(t, testKey, testOwner)) }, errSignature},
+		{"short address", true, testOwner,
+			func() []byte { return setAddress(testPubKey, coinbase[:19]) }, errInvalidAddress},
+		{"unknown validator", true, testOwner,
+			func() []byte { return setAddress(common.HexToHash("0x01"), coinbase[:]) }, errNotRegistered},
+	}
+	for _, test := range tests {
+		k, statedb := newLivenessKeeper(t, test.owned)
+		if err := deliverData(k, statedb, 1, test.from, ether(0), test.data()); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+		if address, ok := k.RegisteredAddress(statedb, tmAddress(testPubKey)); !ok || address != coinbase {
+			t.Errorf("%s: expected %x to be registered, got %x", test.name, coinbase, address)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package staking
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// Slash is the outcome of slashing a validator
+// #unstable
+type Slash struct {
+	PubKey common.Hash
+	Amount *big.Int // burned stake
+}
+
+// SlashedTopic is the first topic of the log emitted from Address for a
+// slashing, as for the event Slashed(bytes32 indexed pubKey, uint256 amount)
+// #unstable
+var SlashedTopic = crypto.Keccak256Hash([]byte("Slashed(bytes32,uint256)"))
+
+// Log returns the ethereum log of the slashing, as served by
+// pluto_getStakingLogs
+// #unstable
+func (s *Slash) Log() *ethTypes.Log {
+	return &ethTypes.Log{
+		Address: Address,
+		Topics:  []common.Hash{SlashedTopic, s.PubKey},
+		Data:    common.LeftPadBytes(s.Amount.Bytes(), 32),
+	}
+}
+
+// tmAddress returns the tendermint address of an ed25519 public key
+func tmAddress(pubKey common.Hash) []byte {
+	var key ed25519.PubKeyEd25519
+	copy(key[:], pubKey[:])
+	return key.Address()
+}
+
+// SlashDoubleSign burns the configured share of the stake of the validator
+// with the given tendermint address and jails it for good. It returns nil if
+// the address does not belong to a registered validator, or if the validator
+// has already been jailed for good.
+// #unstable
+func (k *Keeper) SlashDoubleSign(statedb *state.StateDB, address []byte) *Slash {
+	if k.contract {
+		return nil
+	}
+	s := store{statedb}
+
+	pubKey, ok := s.pubKeyOf(address)
+	if !ok {
+		return nil
+	}
+	v := s.getValidator(pubKey)
+	if v == nil || v.Tombstoned {
+		return nil
+	}
+
+	// Stakes that are still bonding or unbonding were at stake when the
+	// evidence was committed, so they are slashed as well.
+	burned := new(big.Int)
+	for _, stake := range []*big.Int{v.Bonded, v.Pending, v.Unbonding} {
+		amount := new(big.Int).Mul(stake, new(big.Int).SetUint64(k.slashing.DoubleSignSlashPercent))
+		amount.Div(amount, big.NewInt(100))
+		stake.Sub(stake, amount)
+		burned.Add(burned, amount)
+	}
+	statedb.SubBalance(Address, burned)
+
+	v.Jailed = true
+	v.Tombstoned = true
+	s.setValidator(v)
+
+	return &Slash{PubKey: pubKey, Amount: burned}
+}
@@ -0,0 +1,70 @@
+package staking
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+func TestSlashDoubleSign(t *testing.T) {
+	contract := common.HexToAddress("0xcc")
+
+	tests := []struct {
+		name     string
+		contract *common.Address
+		address  []byte
+		slashes  int      // times the evidence is handled
+		burned   *big.Int // by the first slashing, nil for none
+		bonded   *big.Int // left after the slashings
+	}{
+		{"unknown validator", nil, []byte("unknown"), 1, nil, ether(20)},
+		{"staked validator", nil, tmAddress(testPubKey), 1, ether(1), ether(19)},
+		{"tombstoned validator", nil, tmAddress(testPubKey), 3, ether(1), ether(19)},
+		{"validator contract", &contract, tmAddress(testPubKey), 1, nil, ether(20)},
+	}
+	for _, test := range tests {
+		params := plutoTypes.DefaultParams()
+		k := NewKeeper(params)
+		statedb := newTestState(t)
+		if err := deliver(t, k, statedb, 1, testOwner, OpBond, ether(20)); err != nil {
+			t.Fatal(err)
+		}
+		k.EndBlock(statedb, 2)
+
+		// switch to contract mode after bonding
+		k.contract = test.contract != nil
+
+		for i := 0; i < test.slashes; i++ {
+			slash := k.SlashDoubleSign(statedb, test.address)
+			switch {
+			case i > 0 || test.burned == nil:
+				if slash != nil {
+					t.Errorf("%s: slashing %d: expected none, got %v", test.name, i, slash.Amount)
+				}
+			case slash == nil:
+				t.Errorf("%s: expected a slashing", test.name)
+			case slash.PubKey != testPubKey || slash.Amount.Cmp(test.burned) != 0:
+				t.Errorf("%s: expected %v burned from %x, got %v from %x",
+					test.name, test.burned, testPubKey, slash.Amount, slash.PubKey)
+			}
+		}
+
+		v := store{statedb}.getValidator(testPubKey)
+		if v.Bonded.Cmp(test.bonded) != 0 {
+			t.Errorf("%s: expected %v bonded, got %v", test.name, test.bonded, v.Bonded)
+		}
+		if balance := statedb.GetBalance(Address); balance.Cmp(test.bonded) != 0 {
+			t.Errorf("%s: expected a staking balance of %v, got %v", test.name, test.bonded, balance)
+		}
+		if slashed := test.burned != nil; v.Jailed != slashed || v.Tombstoned != slashed {
+			t.Errorf("%s: expected jailed and tombstoned %v, got %v and %v",
+				test.name, slashed, v.Jailed, v.Tombstoned)
+		}
+		if updates := k.EndBlock(statedb, 3); test.burned != nil && (len(updates) != 1 || updates[0].Power != 0) {
+			t.Errorf("%s: expected the validator to lose its power, got %v", test.name, updates)
+		}
+	}
+}
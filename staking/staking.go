@@ -0,0 +1,333 @@
+// Package staking implements native staking and slashing for pluto.
+//
+// Accounts lock ether against a tendermint ed25519 validator key by sending
+// it to the reserved staking address. The tx data selects the operation:
+//
+//	0x01 ++ pubkey (32 bytes) ++ signature (64 bytes)
+//	                           bond tx.value against pubkey
+//	0x02 ++ pubkey (32 bytes)  unbond the whole stake of pubkey
+//	0x03 ++ pubkey (32 bytes) [++ signature (64 bytes)]
+//	                           unjail pubkey after its jail time
+//	0x04 ++ pubkey (32 bytes) ++ address (20 bytes) [++ signature (64 bytes)]
+//	                           register the ethereum address of pubkey
+//
+// The signature of a bond is made with the validator key over the 20 byte
+// address of the sender, so nobody can bond for a key they don't hold. The
+// other operations are reserved to the owner of the validator, unless they
+// carry such a signature as well. Genesis validators without reward address
+// in the genesis file have no owner and are managed that way.
+//
+// A bond counts towards the voting power after the bonding period. An unbonded
+// stake stops counting immediately and is paid back to the owner after the
+// unbonding period. The resulting voting power changes are handed to
+// tendermint in EndBlock.
+//
+// Validators caught double signing lose a share of their stake and are
+// jailed for good, which drops their voting power to zero. Validators that
+// miss too many blocks of the signing window are jailed for a while and can
+// be unjailed afterwards.
+//
+// The genesis validators are registered at InitChain with their genesis
+// power, so they are tracked and jailed like staked validators.
+//
+// If the genesis file configures a validator contract, the contract alone
+// governs the validator set. Bond, unbond and unjail txs are rejected, and
+// neither downtime nor double signing is punished. Only the address registry
+// stays in use.
+package staking
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmTypes "github.com/tendermint/tendermint/types"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+// Address is the reserved system address that holds the stakes
+// #unstable
+var Address = common.HexToAddress("0x0000000000000000000000000000000000001000")
+
+// Operations encoded in the first byte of a staking tx
+const (
+	OpBond       byte = 0x01
+	OpUnbond     byte = 0x02
+	OpUnjail     byte = 0x03
+	OpSetAddress byte = 0x04
+)
+
+// signatureLength is the length of an ed25519 signature
+const signatureLength = 64
+
+// maxPower is the largest voting power tendermint accepts for a validator
+const maxPower = math.MaxInt64 / 8
+
+var (
+	errInvalidData   = errors.New("staking: invalid tx data")
+	errNotOwner      = errors.New("staking: sender is not the owner of the validator")
+	errNoStake       = errors.New("staking: nothing to unbond")
+	errNotRegistered = errors.New("staking: unknown validator")
+	errValue         = errors.New("staking: unbond and unjail must not transfer value")
+	errContract      = errors.New("staking: the validator set is governed by the validator contract")
+	errSignature     = errors.New("staking: invalid validator key signature")
+)
+
+// Validator is the staking record of a validator key
+// #unstable
+type Validator struct {
+	PubKey common.Hash    // ed25519 public key
+	Owner  common.Address // account that bonded the stake
+
+	Bonded        *big.Int // stake counting towards the voting power
+	Pending       *big.Int // stake waiting for the bonding period
+	PendingHeight uint64   // height at which Pending gets bonded
+	Unbonding     *big.Int // stake waiting for the unbonding period
+	UnbondHeight  uint64   // height at which Unbonding is paid back
+
+	GenesisPower int64 // voting power granted at InitChain, without stake
+	Power        int64 // voting power last reported to tendermint
+
+	Jailed      bool   // jailed validators have no voting power
+	JailedUntil uint64 // height from which a jailed validator can unjail
+	Tombstoned  bool   // double signers are jailed for good
+
+	SignCount uint64 // blocks tracked in the signing window
+	Missed    uint64 // blocks missed within the signing window
+	Epoch     uint64 // bumped to clear the signing window
+}
+
+func newValidator(pubKey common.Hash, owner common.Address) *Validator {
+	return &Validator{
+		PubKey:    pubKey,
+		Owner:     owner,
+		Bonded:    new(big.Int),
+		Pending:   new(big.Int),
+		Unbonding: new(big.Int),
+	}
+}
+
+// Keeper applies staking txs and computes validator updates
+// #unstable
+type Keeper struct {
+	params   *plutoTypes.StakingParams
+	slashing *plutoTypes.SlashingParams
+	contract bool // the validator contract governs the validator set
+}
+
+// NewKeeper creates a staking keeper with the given parameters
+// #unstable
+func NewKeeper(params *plutoTypes.Params) *Keeper {
+	return &Keeper{
+		params:   params.Staking,
+		slashing: params.Slashing,
+		contract: params.ValidatorContract != nil,
+	}
+}
+
+// DeliverTx applies a successfully executed tx sent to the staking address.
+// The value has already been transferred to Address by the EVM. If the
+// operation is rejected the value is refunded and an error is returned.
+// #unstable
+func (k *Keeper) DeliverTx(statedb *state.StateDB, height uint64, from common.Address,
+	tx *ethTypes.Transaction) error {
+
+	err := k.deliverTx(store{statedb}, height, from, tx)
+	if err != nil && tx.Value().Sign() > 0 {
+		statedb.SubBalance(Address, tx.Value())
+		statedb.AddBalance(from, tx.Value())
+	}
+	return err
+}
+
+func (k *Keeper) deliverTx(s store, height uint64, from common.Address,
+	tx *ethTypes.Transaction) error {
+
+	data := tx.Data()
+	if len(data) < 1+common.HashLength {
+		return errInvalidData
+	}
+	pubKey := common.BytesToHash(data[1 : 1+common.HashLength])
+	args := data[1+common.HashLength:]
+	if k.contract && data[0] != OpSetAddress {
+		return errContract
+	}
+
+	switch data[0] {
+	case OpBond:
+		if len(args) != signatureLength {
+			return errInvalidData
+		}
+		if !verifyKey(pubKey, from, args) {
+			return errSignature
+		}
+		return k.bond(s, height, from, pubKey, tx.Value())
+	case OpUnbond:
+		if len(args) != 0 {
+			return errInvalidData
+		}
+		if tx.Value().Sign() != 0 {
+			return errValue
+		}
+		return k.unbond(s, height, from, pubKey)
+	case OpUnjail:
+		if len(args) != 0 && len(args) != signatureLength {
+			return errInvalidData
+		}
+		if tx.Value().Sign() != 0 {
+			return errValue
+		}
+		return k.unjail(s, height, from, pubKey, args)
+	case OpSetAddress:
+		if tx.Value().Sign() != 0 {
+			return errValue
+		}
+		return k.setAddress(s, from, pubKey, args)
+	default:
+		return errInvalidData
+	}
+}
+
+// verifyKey checks that signature was made by the validator key pubKey over
+// the address of the sender
+func verifyKey(pubKey common.Hash, from common.Address, signature []byte) bool {
+	var key ed25519.PubKeyEd25519
+	copy(key[:], pubKey[:])
+	return key.VerifyBytes(from[:], signature)
+}
+
+// authorize checks that the sender may manage the validator. Without
+// signature it has to be the owner, otherwise the signature has to be made by
+// the validator key over the sender address.
+func authorize(v *Validator, from common.Address, signature []byte) error {
+	if len(signature) == 0 {
+		if v.Owner != from {
+			return errNotOwner
+		}
+		return nil
+	}
+	if !verifyKey(v.PubKey, from, signature) {
+		return errSignature
+	}
+	return nil
+}
+
+func (k *Keeper) bond(s store, height uint64, from common.Address, pubKey common.Hash,
+	amount *big.Int) error {
+
+	v := s.getValidator(pubKey)
+	if v == nil {
+		v = newValidator(pubKey, from)
+		s.addValidator(pubKey)
+		if _, ok := s.getRegistered(tmAddress(pubKey)); !ok {
+			s.setRegistered(tmAddress(pubKey), from)
+		}
+	} else if v.Owner != from {
+		return errNotOwner
+	}
+
+	stake := new(big.Int).Add(v.Bonded, v.Pending)
+	stake.Add(stake, amount)
+	if minStake := (*big.Int)(k.params.MinStake); stake.Cmp(minStake) < 0 {
+		return fmt.Errorf("staking: stake %v below minimum %v", stake, minStake)
+	}
+
+	v.Pending.Add(v.Pending, amount)
+	v.PendingHeight = height + k.params.BondingPeriod
+	s.setValidator(v)
+	return nil
+}
+
+func (k *Keeper) unbond(s store, height uint64, from common.Address, pubKey common.Hash) error {
+	v := s.getValidator(pubKey)
+	if v == nil {
+		return errNoStake
+	}
+	if v.Owner != from {
+		return errNotOwner
+	}
+	if v.Bonded.Sign() == 0 && v.Pending.Sign() == 0 {
+		return errNoStake
+	}
+
+	v.Unbonding.Add(v.Unbonding, v.Bonded)
+	v.Unbonding.Add(v.Unbonding, v.Pending)
+	v.Bonded.SetUint64(0)
+	v.Pending.SetUint64(0)
+	v.UnbondHeight = height + k.params.UnbondingPeriod
+	s.setValidator(v)
+	return nil
+}
+
+// EndBlock matures pending bonds, pays back unbonded stakes whose unbonding
+// period is over and returns the validators whose voting power changed.
+// #unstable
+func (k *Keeper) EndBlock(statedb *state.StateDB, height uint64) []abciTypes.ValidatorUpdate {
+	if k.contract {
+		return nil
+	}
+	s := store{statedb}
+
+	var updates []abciTypes.ValidatorUpdate
+	count := s.validatorCount()
+	for i := uint64(0); i < count; i++ {
+		v := s.getValidator(s.validatorAt(i))
+		if v == nil {
+			continue
+		}
+		changed := false
+
+		if v.Pending.Sign() > 0 && height >= v.PendingHeight {
+			v.Bonded.Add(v.Bonded, v.Pending)
+			v.Pending.SetUint64(0)
+			changed = true
+		}
+		if v.Unbonding.Sign() > 0 && height >= v.UnbondHeight {
+			statedb.SubBalance(Address, v.Unbonding)
+			statedb.AddBalance(v.Owner, v.Unbonding)
+			v.Unbonding.SetUint64(0)
+			changed = true
+		}
+		if power := k.power(v); power != v.Power {
+			v.Power = power
+			updates = append(updates, validatorUpdate(v))
+			changed = true
+		}
+
+		if changed {
+			s.setValidator(v)
+		}
+	}
+	return updates
+}
+
+// power converts the bonded stake into voting power
+func (k *Keeper) power(v *Validator) int64 {
+	if v.Jailed {
+		return 0
+	}
+	power := new(big.Int).Div(v.Bonded, (*big.Int)(k.params.PowerUnit))
+	power.Add(power, big.NewInt(v.GenesisPower))
+	if !power.IsInt64() || power.Int64() > maxPower {
+		return maxPower
+	}
+	return power.Int64()
+}
+
+func validatorUpdate(v *Validator) abciTypes.ValidatorUpdate {
+	return abciTypes.ValidatorUpdate{
+		PubKey: abciTypes.PubKey{
+			Type: tmTypes.ABCIPubKeyTypeEd25519,
+			Data: common.CopyBytes(v.PubKey[:]),
+		},
+		Power: v.Power,
+	}
+}
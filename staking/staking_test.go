@@ -0,0 +1,178 @@
+package staking
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
+)
+
+var (
+	testOwner  = common.HexToAddress("0x0a")
+	testKey    = ed25519.GenPrivKeyFromSecret([]byte("pluto"))
+	testPubKey = pubKeyHash(testKey)
+)
+
+func pubKeyHash(key ed25519.PrivKeyEd25519) common.Hash {
+	pubKey := key.PubKey().(ed25519.PubKeyEd25519)
+	return common.BytesToHash(pubKey[:])
+}
+
+// sign signs the sender address with the validator key
+func sign(t *testing.T, key ed25519.PrivKeyEd25519, from common.Address) []byte {
+	signature, err := key.Sign(from[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+func newTestState(t *testing.T) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+// deliverData transfers the value to Address like the EVM and hands a tx
+// with the given data to the keeper
+func deliverData(k *Keeper, statedb *state.StateDB, height uint64, from common.Address,
+	value *big.Int, data []byte) error {
+
+	tx := ethTypes.NewTransaction(0, Address, value, 100000, big.NewInt(1), data)
+	statedb.AddBalance(Address, value)
+	return k.DeliverTx(statedb, height, from, tx)
+}
+
+// deliver sends an operation on testPubKey. Bonds are signed with testKey.
+func deliver(t *testing.T, k *Keeper, statedb *state.StateDB, height uint64, from common.Address,
+	op byte, value *big.Int) error {
+
+	data := append([]byte{op}, testPubKey[:]...)
+	if op == OpBond {
+		data = append(data, sign(t, testKey, from)...)
+	}
+	return deliverData(k, statedb, height, from, value, data)
+}
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.Ether))
+}
+
+func TestBondUnbond(t *testing.T) {
+	k := NewKeeper(plutoTypes.DefaultParams())
+	statedb := newTestState(t)
+
+	if err := deliver(t, k, statedb, 1, testOwner, OpBond, ether(3)); err != nil {
+		t.Fatal(err)
+	}
+	if updates := k.EndBlock(statedb, 1); len(updates) != 0 {
+		t.Fatalf("expected no updates during the bonding period, got %v", updates)
+	}
+	updates := k.EndBlock(statedb, 2)
+	if len(updates) != 1 || updates[0].Power != 3 {
+		t.Fatalf("expected power 3, got %v", updates)
+	}
+	if updates := k.EndBlock(statedb, 3); len(updates) != 0 {
+		t.Fatalf("expected no updates without changes, got %v", updates)
+	}
+	if address, ok := k.RegisteredAddress(statedb, tmAddress(testPubKey)); !ok || address != testOwner {
+		t.Errorf("expected the owner to be registered, got %x", address)
+	}
+
+	if err := deliver(t, k, statedb, 4, common.HexToAddress("0x0b"), OpUnbond, new(big.Int)); err != errNotOwner {
+		t.Errorf("expected %v, got %v", errNotOwner, err)
+	}
+	if err := deliver(t, k, statedb, 4, testOwner, OpUnbond, new(big.Int)); err != nil {
+		t.Fatal(err)
+	}
+	updates = k.EndBlock(statedb, 4)
+	if len(updates) != 1 || updates[0].Power != 0 {
+		t.Fatalf("expected power 0, got %v", updates)
+	}
+
+	// the stake is paid back after the unbonding period
+	unbondHeight := 4 + plutoTypes.DefaultStakingParams().UnbondingPeriod
+	k.EndBlock(statedb, unbondHeight-1)
+	if balance := statedb.GetBalance(testOwner); balance.Sign() != 0 {
+		t.Fatalf("expected the stake to be locked, got %v", balance)
+	}
+	k.EndBlock(statedb, unbondHeight)
+	if balance := statedb.GetBalance(testOwner); balance.Cmp(ether(3)) != 0 {
+		t.Errorf("expected the stake to be paid back, got %v", balance)
+	}
+	if balance := statedb.GetBalance(Address); balance.Sign() != 0 {
+		t.Errorf("expected no stake left, got %v", balance)
+	}
+}
+
+func TestRejectedBondIsRefunded(t *testing.T) {
+	k := NewKeeper(plutoTypes.DefaultParams())
+	statedb := newTestState(t)
+
+	if err := deliver(t, k, statedb, 1, testOwner, OpBond, big.NewInt(1)); err == nil {
+		t.Fatal("expected a stake below the minimum to be rejected")
+	}
+	if balance := statedb.GetBalance(testOwner); balance.Int64() != 1 {
+		t.Errorf("expected the value to be refunded, got %v", balance)
+	}
+	if balance := statedb.GetBalance(Address); balance.Sign() != 0 {
+		t.Errorf("expected no stake, got %v", balance)
+	}
+}
+
+func TestContractMode(t *testing.T) {
+	params := plutoTypes.DefaultParams()
+	contract := common.HexToAddress("0xcc")
+	params.ValidatorContract = &contract
+	k := NewKeeper(params)
+	statedb := newTestState(t)
+
+	if err := deliver(t, k, statedb, 1, testOwner, OpBond, ether(3)); err != errContract {
+		t.Fatalf("expected %v, got %v", errContract, err)
+	}
+	if balance := statedb.GetBalance(testOwner); balance.Cmp(ether(3)) != 0 {
+		t.Errorf("expected the value to be refunded, got %v", balance)
+	}
+	if updates := k.EndBlock(statedb, 2); updates != nil {
+		t.Errorf("expected no updates, got %v", updates)
+	}
+}
+
+func TestBondSignature(t *testing.T) {
+	otherKey := ed25519.GenPrivKeyFromSecret([]byte("other"))
+	bond := func(signature []byte) []byte {
+		return append(append([]byte{OpBond}, testPubKey[:]...), signature...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"signed by the validator key", bond(sign(t, testKey, testOwner)), nil},
+		{"signed by another key", bond(sign(t, otherKey, testOwner)), errSignature},
+		{"signed for another owner", bond(sign(t, testKey, common.HexToAddress("0x0b"))), errSignature},
+		{"without signature", bond(nil), errInvalidData},
+		{"truncated signature", bond(sign(t, testKey, testOwner)[:32]), errInvalidData},
+	}
+	for _, test := range tests {
+		k := NewKeeper(plutoTypes.DefaultParams())
+		statedb := newTestState(t)
+		if err := deliverData(k, statedb, 1, testOwner, ether(3), test.data); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		if bonded := k.EndBlock(statedb, 2); (len(bonded) == 1) != (test.err == nil) {
+			t.Errorf("%s: unexpected updates %v", test.name, bonded)
+		}
+	}
+}
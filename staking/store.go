@@ -0,0 +1,194 @@
+package staking
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//----------------------------------------------------------------------
+// The staking records live in the storage of the staking address, so they
+// are part of the state root and survive restarts like any contract state.
+// The layout follows solidity: a record is a run of consecutive slots
+// starting at a hashed base key and a list keeps its length at its base key.
+
+var (
+	validatorsKey      = crypto.Keccak256Hash([]byte("validators"))
+	validatorKeyPrefix = []byte("validator")
+	addressKeyPrefix   = []byte("address")
+	missedKeyPrefix    = []byte("missed")
+)
+
+// store reads and writes slots of the staking address
+type store struct {
+	statedb *state.StateDB
+}
+
+func (s store) get(key common.Hash) common.Hash {
+	return s.statedb.GetState(Address, key)
+}
+
+func (s store) set(key, value common.Hash) {
+	// A nonce keeps the account from being removed as empty (EIP-161)
+	// while its balance is zero.
+	if s.statedb.GetNonce(Address) == 0 {
+		s.statedb.SetNonce(Address, 1)
+	}
+	s.statedb.SetState(Address, key, value)
+}
+
+func (s store) getBig(key common.Hash) *big.Int {
+	return s.get(key).Big()
+}
+
+func (s store) setBig(key common.Hash, value *big.Int) {
+	s.set(key, common.BigToHash(value))
+}
+
+func (s store) getUint64(key common.Hash) uint64 {
+	return s.getBig(key).Uint64()
+}
+
+func (s store) setUint64(key common.Hash, value uint64) {
+	s.setBig(key, new(big.Int).SetUint64(value))
+}
+
+// offset returns the slot i positions after key
+func offset(key common.Hash, i int64) common.Hash {
+	return common.BigToHash(new(big.Int).Add(key.Big(), big.NewInt(i)))
+}
+
+//----------------------------------------------------------------------
+// validator list
+
+// validatorCount returns the number of validators ever registered
+func (s store) validatorCount() uint64 {
+	return s.getUint64(validatorsKey)
+}
+
+// validatorAt returns the public key of the i-th registered validator
+func (s store) validatorAt(i uint64) common.Hash {
+	return s.get(offset(validatorsKey, int64(i)+1))
+}
+
+// addValidator appends a public key to the validator list and indexes it
+// by its tendermint address
+func (s store) addValidator(pubKey common.Hash) {
+	count := s.validatorCount()
+	s.set(offset(validatorsKey, int64(count)+1), pubKey)
+	s.setUint64(validatorsKey, count+1)
+	s.set(addressKey(tmAddress(pubKey)), pubKey)
+}
+
+func addressKey(address []byte) common.Hash {
+	return crypto.Keccak256Hash(addressKeyPrefix, address)
+}
+
+// pubKeyOf returns the public key registered for a tendermint address
+func (s store) pubKeyOf(address []byte) (common.Hash, bool) {
+	pubKey := s.get(addressKey(address))
+	return pubKey, pubKey != (common.Hash{})
+}
+
+//----------------------------------------------------------------------
+// validator records
+
+const (
+	fieldOwner = iota
+	fieldBonded
+	fieldPending
+	fieldPendingHeight
+	fieldUnbonding
+	fieldUnbondHeight
+	fieldPower
+	fieldJailed
+	fieldGenesisPower
+	fieldJailedUntil
+	fieldTombstoned
+	fieldSignCount
+	fieldMissed
+	fieldEpoch
+)
+
+func validatorKey(pubKey common.Hash) common.Hash {
+	return crypto.Keccak256Hash(validatorKeyPrefix, pubKey[:])
+}
+
+// getValidator loads the record of a validator, or nil if it is unknown
+func (s store) getValidator(pubKey common.Hash) *Validator {
+	if registered, _ := s.pubKeyOf(tmAddress(pubKey)); registered != pubKey {
+		return nil
+	}
+	key := validatorKey(pubKey)
+	owner := s.get(offset(key, fieldOwner))
+	return &Validator{
+		PubKey:        pubKey,
+		Owner:         common.BytesToAddress(owner[:]),
+		Bonded:        s.getBig(offset(key, fieldBonded)),
+		Pending:       s.getBig(offset(key, fieldPending)),
+		PendingHeight: s.getUint64(offset(key, fieldPendingHeight)),
+		Unbonding:     s.getBig(offset(key, fieldUnbonding)),
+		UnbondHeight:  s.getUint64(offset(key, fieldUnbondHeight)),
+		Power:         s.getBig(offset(key, fieldPower)).Int64(),
+		Jailed:        s.getUint64(offset(key, fieldJailed)) != 0,
+		GenesisPower:  s.getBig(offset(key, fieldGenesisPower)).Int64(),
+		JailedUntil:   s.getUint64(offset(key, fieldJailedUntil)),
+		Tombstoned:    s.getUint64(offset(key, fieldTombstoned)) != 0,
+		SignCount:     s.getUint64(offset(key, fieldSignCount)),
+		Missed:        s.getUint64(offset(key, fieldMissed)),
+		Epoch:         s.getUint64(offset(key, fieldEpoch)),
+	}
+}
+
+// setValidator stores the record of a validator
+func (s store) setValidator(v *Validator) {
+	key := validatorKey(v.PubKey)
+	s.set(offset(key, fieldOwner), common.BytesToHash(v.Owner[:]))
+	s.setBig(offset(key, fieldBonded), v.Bonded)
+	s.setBig(offset(key, fieldPending), v.Pending)
+	s.setUint64(offset(key, fieldPendingHeight), v.PendingHeight)
+	s.setBig(offset(key, fieldUnbonding), v.Unbonding)
+	s.setUint64(offset(key, fieldUnbondHeight), v.UnbondHeight)
+	s.setBig(offset(key, fieldPower), big.NewInt(v.Power))
+	s.setUint64(offset(key, fieldJailed), boolToUint64(v.Jailed))
+	s.setBig(offset(key, fieldGenesisPower), big.NewInt(v.GenesisPower))
+	s.setUint64(offset(key, fieldJailedUntil), v.JailedUntil)
+	s.setUint64(offset(key, fieldTombstoned), boolToUint64(v.Tombstoned))
+	s.setUint64(offset(key, fieldSignCount), v.SignCount)
+	s.setUint64(offset(key, fieldMissed), v.Missed)
+	s.setUint64(offset(key, fieldEpoch), v.Epoch)
+}
+
+//----------------------------------------------------------------------
+// signing window
+//
+// The window is a ring of slots indexed by SignCount modulo the window size.
+// A slot holds Epoch+1 if the validator missed that block, so bumping the
+// epoch clears the whole window without touching every slot.
+
+func missedKey(pubKey common.Hash, index uint64) common.Hash {
+	return offset(crypto.Keccak256Hash(missedKeyPrefix, pubKey[:]), int64(index))
+}
+
+// getMissed reports whether the validator missed the block in the given slot
+func (s store) getMissed(v *Validator, index uint64) bool {
+	return s.getUint64(missedKey(v.PubKey, index)) == v.Epoch+1
+}
+
+// setMissed records whether the validator missed the block in the given slot
+func (s store) setMissed(v *Validator, index uint64, missed bool) {
+	value := uint64(0)
+	if missed {
+		value = v.Epoch + 1
+	}
+	s.setUint64(missedKey(v.PubKey, index), value)
+}
+
+func boolToUint64(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
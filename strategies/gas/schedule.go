@@ -0,0 +1,35 @@
+package gas
+
+import (
+	emtTypes "github.com/zhuzeyu/pluto/types"
+)
+
+// Schedule governs the block gas limit by the changes scheduled in the
+// genesis file. Between the changes, and before the first one, it keeps the
+// current limit.
+// #unstable
+type Schedule struct {
+	changes []emtTypes.GasLimitChange
+}
+
+// NewSchedule creates a governor that applies the given changes, which have
+// to be ordered by height
+// #unstable
+func NewSchedule(changes []emtTypes.GasLimitChange) *Schedule {
+	return &Schedule{changes: changes}
+}
+
+// MaxGas returns the limit of the last change up to the next block, or the
+// current limit if there is none. Returning the scheduled limit at every
+// block and not only at the changes keeps a replayed chain on schedule.
+// #unstable
+func (s *Schedule) MaxGas(height int64, current int64) int64 {
+	maxGas := current
+	for _, change := range s.changes {
+		if change.Height > height+1 {
+			break
+		}
+		maxGas = change.MaxGas
+	}
+	return maxGas
+}
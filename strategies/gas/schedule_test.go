@@ -0,0 +1,39 @@
+package gas
+
+import (
+	"testing"
+
+	emtTypes "github.com/zhuzeyu/pluto/types"
+)
+
+func TestSchedule(t *testing.T) {
+	strategy := &emtTypes.Strategy{Governor: NewSchedule([]emtTypes.GasLimitChange{
+		{Height: 10, MaxGas: 1000},
+		{Height: 20, MaxGas: 2000},
+	})}
+
+	tests := []struct {
+		height  int64 // of the block that ends
+		current int64
+		maxGas  int64
+		changed bool
+	}{
+		{1, 500, 0, false},
+		{8, 500, 0, false},
+		{9, 500, 1000, true},
+		{10, 1000, 0, false},
+		{15, 1000, 0, false},
+		{19, 1000, 2000, true},
+		{25, 2000, 0, false},
+		// a limit off schedule is brought back to it
+		{12, 700, 1000, true},
+		{30, 700, 2000, true},
+	}
+	for _, test := range tests {
+		maxGas, changed := strategy.MaxGas(test.height, test.current)
+		if changed != test.changed || (changed && maxGas != test.maxGas) {
+			t.Errorf("height %d: expected %d (%v), got %d (%v)",
+				test.height, test.maxGas, test.changed, maxGas, changed)
+		}
+	}
+}
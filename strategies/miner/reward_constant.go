@@ -0,0 +1,23 @@
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RewardConstant pays every block to a fixed operator address
+// #unstable
+type RewardConstant struct {
+	receiver common.Address
+}
+
+// NewRewardConstant creates a strategy that always returns receiver
+// #unstable
+func NewRewardConstant(receiver common.Address) *RewardConstant {
+	return &RewardConstant{receiver: receiver}
+}
+
+// Receiver returns the operator address
+// #unstable
+func (r *RewardConstant) Receiver() common.Address {
+	return r.receiver
+}
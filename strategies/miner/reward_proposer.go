@@ -0,0 +1,38 @@
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+// AddressResolver returns the ethereum address registered for the validator
+// with the given tendermint address
+// #unstable
+type AddressResolver func(validator []byte) (common.Address, bool)
+
+// RewardProposer pays every block to the ethereum address of its proposer
+// #unstable
+type RewardProposer struct {
+	resolve  AddressResolver
+	receiver common.Address
+}
+
+// NewRewardProposer creates a strategy that pays the block proposer
+// #unstable
+func NewRewardProposer(resolve AddressResolver) *RewardProposer {
+	return &RewardProposer{resolve: resolve}
+}
+
+// BeginBlock looks up the address of the proposer of the new block.
+// Proposers without a registered address get the zero address.
+// #unstable
+func (r *RewardProposer) BeginBlock(req abciTypes.RequestBeginBlock) {
+	r.receiver, _ = r.resolve(req.Header.GetProposerAddress())
+}
+
+// Receiver returns the address of the current proposer
+// #unstable
+func (r *RewardProposer) Receiver() common.Address {
+	return r.receiver
+}
@@ -0,0 +1,56 @@
+package miner
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+// RewardRoundRobin pays the blocks to the validators of the last commit in
+// turn, ordered by their tendermint address. Validators without a registered
+// address are skipped.
+// #unstable
+type RewardRoundRobin struct {
+	resolve  AddressResolver
+	receiver common.Address
+}
+
+// NewRewardRoundRobin creates a round-robin strategy over the validators of
+// the last commit
+// #unstable
+func NewRewardRoundRobin(resolve AddressResolver) *RewardRoundRobin {
+	return &RewardRoundRobin{resolve: resolve}
+}
+
+// BeginBlock picks the receiver for the new block from its height. The first
+// block has no last commit and is paid to the zero address.
+// #unstable
+func (r *RewardRoundRobin) BeginBlock(req abciTypes.RequestBeginBlock) {
+	var addresses [][]byte
+	for _, vote := range req.LastCommitInfo.Votes {
+		if vote.Validator.Power > 0 {
+			addresses = append(addresses, vote.Validator.Address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i], addresses[j]) < 0
+	})
+
+	r.receiver = common.Address{}
+	for i := range addresses {
+		idx := (uint64(req.Header.GetHeight()) + uint64(i)) % uint64(len(addresses))
+		if receiver, ok := r.resolve(addresses[idx]); ok {
+			r.receiver = receiver
+			return
+		}
+	}
+}
+
+// Receiver returns the address whose turn it is
+// #unstable
+func (r *RewardRoundRobin) Receiver() common.Address {
+	return r.receiver
+}
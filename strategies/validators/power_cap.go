@@ -0,0 +1,46 @@
+package validators
+
+import (
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+// PowerCap limits the voting power of every validator, so no single
+// validator can dominate the consensus however much it stakes. It is also a
+// minimal example of a validators strategy.
+// #unstable
+type PowerCap struct {
+	maxPower int64
+}
+
+// NewPowerCap creates a strategy that lowers the power of any validator above
+// maxPower to maxPower
+// #unstable
+func NewPowerCap(maxPower int64) *PowerCap {
+	return &PowerCap{maxPower: maxPower}
+}
+
+// InitChain does nothing, the genesis validators are capped at the end of
+// the first block
+// #unstable
+func (p *PowerCap) InitChain(validators []abciTypes.ValidatorUpdate) {}
+
+// DeliverTx does nothing, the strategy only looks at the validator set
+// #unstable
+func (p *PowerCap) DeliverTx(tx *ethTypes.Transaction, receipt *ethTypes.Receipt) {}
+
+// EndBlock returns an update for every validator above the cap
+// #unstable
+func (p *PowerCap) EndBlock(height int64, validators []abciTypes.ValidatorUpdate) []abciTypes.ValidatorUpdate {
+	var updates []abciTypes.ValidatorUpdate
+	for _, validator := range validators {
+		if validator.Power > p.maxPower {
+			updates = append(updates, abciTypes.ValidatorUpdate{
+				PubKey: validator.PubKey,
+				Power:  p.maxPower,
+			})
+		}
+	}
+	return updates
+}
@@ -0,0 +1,46 @@
+package validators
+
+import (
+	"testing"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+
+	emtTypes "github.com/zhuzeyu/pluto/types"
+)
+
+func validator(key byte, power int64) abciTypes.ValidatorUpdate {
+	return abciTypes.ValidatorUpdate{
+		PubKey: abciTypes.PubKey{Type: "ed25519", Data: []byte{key}},
+		Power:  power,
+	}
+}
+
+func TestPowerCapEndBlock(t *testing.T) {
+	p := NewPowerCap(10)
+	updates := p.EndBlock(1, []abciTypes.ValidatorUpdate{
+		validator(1, 5), validator(2, 10), validator(3, 25),
+	})
+	if len(updates) != 1 {
+		t.Fatalf("expected 1 update, got %v", updates)
+	}
+	if updates[0].PubKey.Data[0] != 3 || updates[0].Power != 10 {
+		t.Errorf("expected validator 3 capped at 10, got %v", updates[0])
+	}
+}
+
+func TestPowerCapStrategy(t *testing.T) {
+	strategy := &emtTypes.Strategy{ValidatorsStrategy: NewPowerCap(10)}
+	strategy.InitChain([]abciTypes.ValidatorUpdate{validator(1, 20)})
+
+	strategy.EndBlock(1)
+	updates := strategy.ValidatorUpdates()
+	if len(updates) != 1 || updates[0].Power != 10 {
+		t.Fatalf("expected validator 1 capped at 10, got %v", updates)
+	}
+
+	// capped validators are left alone
+	strategy.EndBlock(2)
+	if updates := strategy.ValidatorUpdates(); len(updates) != 0 {
+		t.Errorf("expected no updates, got %v", updates)
+	}
+}
@@ -0,0 +1,338 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	tmTypes "github.com/tendermint/tendermint/types"
+)
+
+// Params are the pluto specific chain parameters.
+// They are read from the "pluto" section of the genesis file and
+// stored in the chain database by `pluto init`.
+type Params struct {
+	// BlockReward is minted for every block and split between the validators.
+	// It is the initial reward of the emission schedule.
+	BlockReward *math.HexOrDecimal256 `json:"blockReward"`
+
+	// Emission reduces the block reward over time and caps the supply
+	Emission *EmissionParams `json:"emission"`
+
+	// Fees routes the gas fees collected in a block
+	Fees *FeeParams `json:"fees"`
+
+	// Validators maps hex encoded tendermint validator addresses to the
+	// ethereum addresses that collect their rewards. Validate normalizes
+	// the keys to upper case hex without 0x prefix.
+	Validators map[string]common.Address `json:"validators"`
+
+	// Staking configures the native staking module
+	Staking *StakingParams `json:"staking"`
+
+	// Slashing configures the penalties for misbehaving validators
+	Slashing *SlashingParams `json:"slashing"`
+
+	// MinGasPrice is the lowest gas price of a tx that can be included in
+	// a block. Nil means no minimum.
+	MinGasPrice *math.HexOrDecimal256 `json:"minGasPrice"`
+
+	// ValidatorContract, if set, governs the validator set instead of the
+	// staking module. See ValidatorContractABI for the interface it has
+	// to implement.
+	ValidatorContract *common.Address `json:"validatorContract"`
+
+	// Reward selects the coinbase of the blocks
+	Reward *RewardParams `json:"reward"`
+
+	// GasLimits schedules changes of the block gas limit, ordered by height.
+	// The tendermint genesis sets the limit until the first change.
+	GasLimits []GasLimitChange `json:"gasLimits"`
+}
+
+// GasLimitChange sets the block gas limit from a height on
+type GasLimitChange struct {
+	// Height is the first block with the new limit. Block 1 always has the
+	// limit of the tendermint genesis, so changes start at height 2.
+	Height int64 `json:"height"`
+	MaxGas int64 `json:"maxGas"`
+}
+
+// Reward strategies selectable in RewardParams
+const (
+	RewardStrategyFixed      = "fixed"
+	RewardStrategyProposer   = "proposer"
+	RewardStrategyRoundRobin = "roundrobin"
+)
+
+// RewardParams selects the coinbase of the blocks, which collects the fees
+// and whatever of the block reward can't be split between the validators.
+// The coinbase is part of the state root, so it is a consensus parameter.
+type RewardParams struct {
+	// Strategy is one of the RewardStrategy constants
+	Strategy string `json:"strategy"`
+
+	// Address is the receiver of the fixed strategy
+	Address common.Address `json:"address"`
+}
+
+// ValidatorContractABI is the interface of a validator contract. getValidators
+// returns the complete validator set as ed25519 public keys and voting powers.
+const ValidatorContractABI = `[{"constant":true,"inputs":[],"name":"getValidators","outputs":[{"name":"pubKeys","type":"bytes32[]"},{"name":"powers","type":"uint256[]"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// EmissionParams is the monetary policy of the chain
+type EmissionParams struct {
+	// ReductionInterval is the number of blocks after which the block reward
+	// is reduced. Zero keeps the block reward constant.
+	ReductionInterval uint64 `json:"reductionInterval"`
+
+	// ReductionPercent is by how much the block reward is reduced after
+	// every interval: 50 halves it.
+	ReductionPercent uint64 `json:"reductionPercent"`
+
+	// MaxSupply caps the total supply of ether. No more block rewards are
+	// minted once it is reached. Nil means no cap.
+	MaxSupply *math.HexOrDecimal256 `json:"maxSupply"`
+}
+
+// FeeParams routes the gas fees collected in a block. Whatever is not burned,
+// sent to the treasury or split between the validators stays with the coinbase.
+type FeeParams struct {
+	// BurnPercent is the percentage of the fees that is destroyed
+	BurnPercent uint64 `json:"burnPercent"`
+
+	// TreasuryPercent is the percentage of the fees paid to Treasury
+	TreasuryPercent uint64         `json:"treasuryPercent"`
+	Treasury        common.Address `json:"treasury"`
+
+	// ValidatorPercent is the percentage of the fees split between the
+	// validators by voting power
+	ValidatorPercent uint64 `json:"validatorPercent"`
+}
+
+// StakingParams configures the native staking module
+type StakingParams struct {
+	// BondingPeriod is the number of blocks before a new stake counts
+	// towards the voting power
+	BondingPeriod uint64 `json:"bondingPeriod"`
+
+	// UnbondingPeriod is the number of blocks an unbonded stake stays
+	// locked before it is paid back to the owner
+	UnbondingPeriod uint64 `json:"unbondingPeriod"`
+
+	// MinStake is the smallest stake accepted for a validator
+	MinStake *math.HexOrDecimal256 `json:"minStake"`
+
+	// PowerUnit is the stake in wei that makes up one unit of voting power
+	PowerUnit *math.HexOrDecimal256 `json:"powerUnit"`
+}
+
+// SlashingParams configures the penalties for misbehaving validators
+type SlashingParams struct {
+	// DoubleSignSlashPercent is the percentage of the stake burned when a
+	// validator signs two conflicting votes
+	DoubleSignSlashPercent uint64 `json:"doubleSignSlashPercent"`
+
+	// SignedBlocksWindow is the number of recent blocks in which the
+	// signatures of a validator are tracked
+	SignedBlocksWindow uint64 `json:"signedBlocksWindow"`
+
+	// MaxMissedPercent is the percentage of the window a validator may miss
+	// before it is jailed
+	MaxMissedPercent uint64 `json:"maxMissedPercent"`
+
+	// DowntimeJailDuration is the number of blocks a validator jailed for
+	// downtime has to wait before it can unjail
+	DowntimeJailDuration uint64 `json:"downtimeJailDuration"`
+}
+
+// DefaultParams returns the parameters of a chain without a "pluto"
+// genesis section: no block reward and all fees to the coinbase.
+func DefaultParams() *Params {
+	return &Params{
+		BlockReward: (*math.HexOrDecimal256)(new(big.Int)),
+		Emission:    new(EmissionParams),
+		Fees:        new(FeeParams),
+		Validators:  make(map[string]common.Address),
+		Staking:     DefaultStakingParams(),
+		Slashing:    DefaultSlashingParams(),
+		Reward:      &RewardParams{Strategy: RewardStrategyProposer},
+	}
+}
+
+// DefaultStakingParams returns the staking parameters used when the genesis
+// file does not configure staking: one voting power per ether, bonded after
+// one block and paid back a thousand blocks after unbonding.
+func DefaultStakingParams() *StakingParams {
+	return &StakingParams{
+		BondingPeriod:   1,
+		UnbondingPeriod: 1000,
+		MinStake:        (*math.HexOrDecimal256)(big.NewInt(params.Ether)),
+		PowerUnit:       (*math.HexOrDecimal256)(big.NewInt(params.Ether)),
+	}
+}
+
+// DefaultSlashingParams returns the slashing parameters used when the genesis
+// file does not configure slashing: five percent for double signing, and
+// jail for 600 blocks when missing more than half of the last 100 blocks.
+func DefaultSlashingParams() *SlashingParams {
+	return &SlashingParams{
+		DoubleSignSlashPercent: 5,
+		SignedBlocksWindow:     100,
+		MaxMissedPercent:       50,
+		DowntimeJailDuration:   600,
+	}
+}
+
+// Validate checks the parameters for consistency and normalizes the keys of
+// Validators
+func (p *Params) Validate() error {
+	if p.BlockReward != nil && (*big.Int)(p.BlockReward).Sign() < 0 {
+		return fmt.Errorf("negative block reward")
+	}
+	if p.Emission == nil {
+		return fmt.Errorf("missing emission params")
+	}
+	if p.Emission.ReductionPercent > 100 {
+		return fmt.Errorf("reward reduction %d exceeds 100 percent", p.Emission.ReductionPercent)
+	}
+	if p.Emission.MaxSupply != nil && (*big.Int)(p.Emission.MaxSupply).Sign() < 0 {
+		return fmt.Errorf("negative max supply")
+	}
+	if err := p.normalizeValidators(); err != nil {
+		return err
+	}
+	if p.Fees == nil {
+		return fmt.Errorf("missing fee params")
+	}
+	if p.Fees.BurnPercent+p.Fees.TreasuryPercent+p.Fees.ValidatorPercent > 100 {
+		return fmt.Errorf("fee shares exceed 100 percent")
+	}
+	if p.Fees.TreasuryPercent > 0 && p.Fees.Treasury == (common.Address{}) {
+		return fmt.Errorf("treasury fee share without treasury address")
+	}
+	if p.Staking == nil {
+		return fmt.Errorf("missing staking params")
+	}
+	if p.Staking.PowerUnit == nil || (*big.Int)(p.Staking.PowerUnit).Sign() <= 0 {
+		return fmt.Errorf("staking power unit must be positive")
+	}
+	if p.Staking.MinStake == nil || (*big.Int)(p.Staking.MinStake).Sign() < 0 {
+		return fmt.Errorf("staking min stake must not be negative")
+	}
+	if p.Slashing == nil {
+		return fmt.Errorf("missing slashing params")
+	}
+	if p.Slashing.DoubleSignSlashPercent > 100 {
+		return fmt.Errorf("double sign slash %d exceeds 100 percent", p.Slashing.DoubleSignSlashPercent)
+	}
+	if p.Slashing.SignedBlocksWindow == 0 {
+		return fmt.Errorf("signed blocks window must be positive")
+	}
+	if p.Slashing.MaxMissedPercent > 100 {
+		return fmt.Errorf("max missed %d exceeds 100 percent", p.Slashing.MaxMissedPercent)
+	}
+	if p.Reward == nil {
+		return fmt.Errorf("missing reward params")
+	}
+	switch p.Reward.Strategy {
+	case RewardStrategyProposer, RewardStrategyRoundRobin:
+	case RewardStrategyFixed:
+		if p.Reward.Address == (common.Address{}) {
+			return fmt.Errorf("fixed reward strategy without address")
+		}
+	default:
+		return fmt.Errorf("unknown reward strategy %q", p.Reward.Strategy)
+	}
+	for i, change := range p.GasLimits {
+		if change.Height < 2 || (i > 0 && change.Height <= p.GasLimits[i-1].Height) {
+			return fmt.Errorf("gas limit change at height %d out of order", change.Height)
+		}
+		if change.MaxGas <= 0 {
+			return fmt.Errorf("gas limit change at height %d must be positive", change.Height)
+		}
+	}
+	return nil
+}
+
+// GetBlockReward returns the initial block reward, which is never nil
+func (p *Params) GetBlockReward() *big.Int {
+	if p.BlockReward == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set((*big.Int)(p.BlockReward))
+}
+
+// BlockRewardAt returns the block reward at the given height according to
+// the emission schedule. The max supply is not taken into account.
+func (p *Params) BlockRewardAt(height uint64) *big.Int {
+	reward := p.GetBlockReward()
+	if p.Emission.ReductionInterval == 0 || p.Emission.ReductionPercent == 0 || height == 0 {
+		return reward
+	}
+	keep := big.NewInt(int64(100 - p.Emission.ReductionPercent))
+	for i := (height - 1) / p.Emission.ReductionInterval; i > 0 && reward.Sign() > 0; i-- {
+		reward.Mul(reward, keep)
+		reward.Div(reward, big.NewInt(100))
+	}
+	return reward
+}
+
+// GetMinGasPrice returns the consensus minimum gas price, which is never nil
+func (p *Params) GetMinGasPrice() *big.Int {
+	if p.MinGasPrice == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set((*big.Int)(p.MinGasPrice))
+}
+
+// MaxSupply returns the supply cap, or nil if there is none
+func (p *Params) MaxSupply() *big.Int {
+	if p.Emission.MaxSupply == nil {
+		return nil
+	}
+	return (*big.Int)(p.Emission.MaxSupply)
+}
+
+// normalizeValidators rewrites the keys of Validators to upper case hex, so
+// they can be looked up directly. Keys naming the same validator twice are
+// rejected.
+func (p *Params) normalizeValidators() error {
+	validators := make(map[string]common.Address, len(p.Validators))
+	for key, address := range p.Validators {
+		validator, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(key), "0x"))
+		if err != nil || len(validator) != crypto.AddressSize {
+			return fmt.Errorf("invalid validator address %q", key)
+		}
+		normalized := fmt.Sprintf("%X", validator)
+		if _, ok := validators[normalized]; ok {
+			return fmt.Errorf("duplicate validator address %q", key)
+		}
+		validators[normalized] = address
+	}
+	p.Validators = validators
+	return nil
+}
+
+// RewardAddress returns the ethereum address registered for the validator
+// with the given tendermint address
+func (p *Params) RewardAddress(validator []byte) (common.Address, bool) {
+	address, ok := p.Validators[fmt.Sprintf("%X", validator)]
+	return address, ok
+}
+
+// ValidatorAddress returns the tendermint address of a validator
+func ValidatorAddress(validator abciTypes.ValidatorUpdate) ([]byte, error) {
+	pubKey, err := tmTypes.PB2TM.PubKey(validator.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey.Address(), nil
+}
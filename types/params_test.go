@@ -0,0 +1,114 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+func TestBlockRewardAt(t *testing.T) {
+	params := DefaultParams()
+	params.BlockReward = (*math.HexOrDecimal256)(big.NewInt(1000))
+
+	// no emission schedule, the reward stays the same
+	for _, height := range []uint64{0, 1, 1000000} {
+		if reward := params.BlockRewardAt(height); reward.Int64() != 1000 {
+			t.Errorf("height %d: expected 1000, got %v", height, reward)
+		}
+	}
+
+	params.Emission.ReductionInterval = 10
+	params.Emission.ReductionPercent = 50
+	expected := map[uint64]int64{
+		0:    1000,
+		1:    1000,
+		10:   1000,
+		11:   500,
+		20:   500,
+		21:   250,
+		31:   125,
+		41:   62,
+		1001: 0,
+	}
+	for height, want := range expected {
+		if reward := params.BlockRewardAt(height); reward.Int64() != want {
+			t.Errorf("height %d: expected %d, got %v", height, want, reward)
+		}
+	}
+
+	// the initial reward is left alone
+	if reward := params.GetBlockReward(); reward.Int64() != 1000 {
+		t.Errorf("expected the initial reward 1000, got %v", reward)
+	}
+}
+
+func TestRewardAddress(t *testing.T) {
+	validator := common.FromHex("0x0102030405060708090a0b0c0d0e0f1011121314")
+	owner := common.HexToAddress("0x0a")
+
+	for _, key := range []string{
+		"0102030405060708090A0B0C0D0E0F1011121314",
+		"0102030405060708090a0b0c0d0e0f1011121314",
+		"0x0102030405060708090a0B0C0D0E0F1011121314",
+		"0X0102030405060708090A0B0C0D0E0F1011121314",
+	} {
+		params := DefaultParams()
+		params.Validators[key] = owner
+		if err := params.Validate(); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if address, ok := params.RewardAddress(validator); !ok || address != owner {
+			t.Errorf("%s: expected %x, got %x", key, owner, address)
+		}
+	}
+
+	params := DefaultParams()
+	if _, ok := params.RewardAddress(validator); ok {
+		t.Error("expected no address without validators")
+	}
+}
+
+func TestValidateValidators(t *testing.T) {
+	tests := []struct {
+		keys []string
+		ok   bool
+	}{
+		{[]string{"0102030405060708090a0b0c0d0e0f1011121314", "1102030405060708090a0b0c0d0e0f1011121314"}, true},
+		{[]string{"0102030405060708090a0b0c0d0e0f1011121314", "0x0102030405060708090A0B0C0D0E0F1011121314"}, false},
+		{[]string{"0102030405060708090a0b0c0d0e0f10111213"}, false},
+		{[]string{"0102030405060708090a0b0c0d0e0f101112131g"}, false},
+	}
+	for i, test := range tests {
+		params := DefaultParams()
+		for _, key := range test.keys {
+			params.Validators[key] = common.HexToAddress("0x0a")
+		}
+		if err := params.Validate(); (err == nil) != test.ok {
+			t.Errorf("test %d: expected ok %v, got %v", i, test.ok, err)
+		}
+	}
+}
+
+func TestValidateGasLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []GasLimitChange
+		ok      bool
+	}{
+		{"none", nil, true},
+		{"ordered", []GasLimitChange{{2, 1000}, {10, 500}}, true},
+		{"at the first block", []GasLimitChange{{1, 1000}}, false},
+		{"out of order", []GasLimitChange{{10, 1000}, {5, 500}}, false},
+		{"same height", []GasLimitChange{{10, 1000}, {10, 500}}, false},
+		{"zero limit", []GasLimitChange{{10, 0}}, false},
+	}
+	for _, test := range tests {
+		params := DefaultParams()
+		params.GasLimits = test.changes
+		if err := params.Validate(); (err == nil) != test.ok {
+			t.Errorf("%s: expected ok %v, got %v", test.name, test.ok, err)
+		}
+	}
+}
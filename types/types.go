@@ -1,10 +1,11 @@
 package types
 
 import (
+	"bytes"
+
 	"github.com/ethereum/go-ethereum/common"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 
-	"github.com/tendermint/tendermint/abci/types"
 	abciTypes "github.com/tendermint/tendermint/abci/types"
 )
 
@@ -13,21 +14,210 @@ type MinerRewardStrategy interface {
 	Receiver() common.Address
 }
 
-// ValidatorsStrategy is a validator strategy
+// BlockAware is implemented by strategies that need to know about every new
+// block, e.g. to pick the receiver from the proposer or the height. They
+// should only rely on the request, which is the same on every node, also
+// after a restart.
+type BlockAware interface {
+	BeginBlock(req abciTypes.RequestBeginBlock)
+}
+
+// ValidatorsStrategy is a validator strategy. It is driven by the ABCI calls
+// of the same name and only deals in plain values, so it can be tested
+// without a running chain.
 type ValidatorsStrategy interface {
-	SetValidators(validators []*types.Validator)
-	CollectTx(tx *ethTypes.Transaction)
-	GetUpdatedValidators() []*types.Validator
+	// InitChain is called with the genesis validators
+	InitChain(validators []abciTypes.ValidatorUpdate)
+
+	// DeliverTx is called with every successfully delivered tx and its receipt
+	DeliverTx(tx *ethTypes.Transaction, receipt *ethTypes.Receipt)
+
+	// EndBlock is called at the end of every block with the current validator
+	// set and returns the validator updates of the strategy. A validator with
+	// zero power is removed.
+	EndBlock(height int64, validators []abciTypes.ValidatorUpdate) []abciTypes.ValidatorUpdate
 }
 
-// Strategy encompasses all available strategies
+// GasLimitGovernor governs the block gas limit. MaxGas is called at the end
+// of every block with the current limit and returns the limit from the next
+// block on. It is set as the Governor of the Strategy, or implemented by the
+// validators strategy.
+type GasLimitGovernor interface {
+	MaxGas(height int64, current int64) int64
+}
+
+// Strategy encompasses all available strategies. It keeps track of the
+// current validator set and the updates to report to tendermint.
 type Strategy struct {
 	MinerRewardStrategy
 	ValidatorsStrategy
 
+	// Governor, if set, decides the block gas limit in place of the
+	// validators strategy
+	Governor GasLimitGovernor
+
 	curValidators []abciTypes.ValidatorUpdate
+	updates       []pendingUpdate
+}
+
+// pendingUpdate is the last update of a validator since the updates were
+// last fetched
+type pendingUpdate struct {
+	abciTypes.ValidatorUpdate
+	added bool // the validator wasn't in the set before the first update
+}
+
+// InitChain sets the genesis validators and hands them to the validators
+// strategy
+func (strategy *Strategy) InitChain(validators []abciTypes.ValidatorUpdate) {
+	strategy.curValidators = append([]abciTypes.ValidatorUpdate(nil), validators...)
+	if strategy.ValidatorsStrategy != nil {
+		strategy.ValidatorsStrategy.InitChain(validators)
+	}
+}
+
+// RestoreValidators sets the current validator set after a restart, when
+// InitChain isn't called
+func (strategy *Strategy) RestoreValidators(validators []abciTypes.ValidatorUpdate) {
+	strategy.curValidators = append([]abciTypes.ValidatorUpdate(nil), validators...)
+}
+
+// BeginBlock tells the strategies that implement BlockAware about a new block
+func (strategy *Strategy) BeginBlock(req abciTypes.RequestBeginBlock) {
+	if s, ok := strategy.MinerRewardStrategy.(BlockAware); ok {
+		s.BeginBlock(req)
+	}
+	if s, ok := strategy.ValidatorsStrategy.(BlockAware); ok {
+		s.BeginBlock(req)
+	}
+}
+
+// DeliverTx hands a delivered tx to the validators strategy
+func (strategy *Strategy) DeliverTx(tx *ethTypes.Transaction, receipt *ethTypes.Receipt) {
+	if strategy.ValidatorsStrategy != nil {
+		strategy.ValidatorsStrategy.DeliverTx(tx, receipt)
+	}
+}
+
+// EndBlock applies the updates of the validators strategy to the current
+// validator set
+func (strategy *Strategy) EndBlock(height int64) {
+	if strategy.ValidatorsStrategy != nil {
+		strategy.UpdateValidators(strategy.ValidatorsStrategy.EndBlock(height, strategy.Validators()))
+	}
+}
+
+// MaxGas asks the Governor, or a validators strategy that is a
+// GasLimitGovernor, for the gas limit of the next block. It returns false if
+// the limit doesn't change.
+func (strategy *Strategy) MaxGas(height int64, current int64) (int64, bool) {
+	governor := strategy.Governor
+	if governor == nil {
+		var ok bool
+		if governor, ok = strategy.ValidatorsStrategy.(GasLimitGovernor); !ok {
+			return 0, false
+		}
+	}
+	maxGas := governor.MaxGas(height, current)
+	return maxGas, maxGas > 0 && maxGas != current
+}
+
+// Validators returns a copy of the current validator set
+func (strategy *Strategy) Validators() []abciTypes.ValidatorUpdate {
+	return append([]abciTypes.ValidatorUpdate(nil), strategy.curValidators...)
+}
+
+// UpdateValidators applies validator updates to the current validator set.
+// A validator with zero power is removed. The updates are kept until they
+// are fetched with ValidatorUpdates.
+func (strategy *Strategy) UpdateValidators(updates []abciTypes.ValidatorUpdate) {
+	for _, update := range updates {
+		strategy.addUpdate(update)
+		idx := -1
+		for i, validator := range strategy.curValidators {
+			if pubKeyEqual(validator.PubKey, update.PubKey) {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case idx < 0 && update.Power > 0:
+			strategy.curValidators = append(strategy.curValidators, update)
+		case idx >= 0 && update.Power > 0:
+			strategy.curValidators[idx] = update
+		case idx >= 0:
+			strategy.curValidators = append(strategy.curValidators[:idx],
+				strategy.curValidators[idx+1:]...)
+		}
+	}
+}
+
+// addUpdate records an update, replacing an earlier one of the same validator.
+// It has to be called before the update is applied to the validator set.
+func (strategy *Strategy) addUpdate(update abciTypes.ValidatorUpdate) {
+	for i := range strategy.updates {
+		if pubKeyEqual(strategy.updates[i].PubKey, update.PubKey) {
+			strategy.updates[i].ValidatorUpdate = update
+			return
+		}
+	}
+	strategy.updates = append(strategy.updates, pendingUpdate{
+		ValidatorUpdate: update,
+		added:           strategy.find(update.PubKey) == nil,
+	})
+}
+
+// ValidatorUpdates returns and clears the updates applied since the last
+// call. There is at most one update per validator, the last one. Validators
+// that were added and removed again in between are left out, tendermint
+// never knew them.
+func (strategy *Strategy) ValidatorUpdates() []abciTypes.ValidatorUpdate {
+	var updates []abciTypes.ValidatorUpdate
+	for _, update := range strategy.updates {
+		if update.added && update.Power == 0 {
+			continue
+		}
+		updates = append(updates, update.ValidatorUpdate)
+	}
+	strategy.updates = nil
+	return updates
+}
+
+// Diff returns the updates that turn the current validator set into the
+// given one: changed and new validators in the given order, followed by the
+// removed ones with zero power.
+func (strategy *Strategy) Diff(validators []abciTypes.ValidatorUpdate) []abciTypes.ValidatorUpdate {
+	var updates []abciTypes.ValidatorUpdate
+	for _, validator := range validators {
+		if current := strategy.find(validator.PubKey); current == nil ||
+			current.Power != validator.Power {
+			updates = append(updates, validator)
+		}
+	}
+	for _, current := range strategy.curValidators {
+		found := false
+		for _, validator := range validators {
+			if pubKeyEqual(current.PubKey, validator.PubKey) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			updates = append(updates, abciTypes.ValidatorUpdate{PubKey: current.PubKey, Power: 0})
+		}
+	}
+	return updates
+}
+
+func (strategy *Strategy) find(pubKey abciTypes.PubKey) *abciTypes.ValidatorUpdate {
+	for i, validator := range strategy.curValidators {
+		if pubKeyEqual(validator.PubKey, pubKey) {
+			return &strategy.curValidators[i]
+		}
+	}
+	return nil
 }
 
-func (strategy *Strategy) SetValidators(validators []abciTypes.ValidatorUpdate) {
-	strategy.curValidators = validators
+func pubKeyEqual(a, b abciTypes.PubKey) bool {
+	return a.Type == b.Type && bytes.Equal(a.Data, b.Data)
 }
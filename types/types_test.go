@@ -0,0 +1,84 @@
+package types
+
+import (
+	"testing"
+
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+func validator(key byte, power int64) abciTypes.ValidatorUpdate {
+	return abciTypes.ValidatorUpdate{
+		PubKey: abciTypes.PubKey{Type: "ed25519", Data: []byte{key}},
+		Power:  power,
+	}
+}
+
+func TestUpdateValidators(t *testing.T) {
+	strategy := &Strategy{}
+	strategy.InitChain([]abciTypes.ValidatorUpdate{validator(1, 10), validator(2, 10)})
+
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(1, 20), validator(3, 5)})
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(2, 0), validator(1, 30)})
+
+	validators := strategy.Validators()
+	if len(validators) != 2 {
+		t.Fatalf("expected 2 validators, got %v", validators)
+	}
+	if v := strategy.find(validator(1, 0).PubKey); v == nil || v.Power != 30 {
+		t.Errorf("expected validator 1 with power 30, got %v", v)
+	}
+	if v := strategy.find(validator(2, 0).PubKey); v != nil {
+		t.Errorf("expected validator 2 removed, got %v", v)
+	}
+
+	// one update per validator, the last one
+	updates := strategy.ValidatorUpdates()
+	expected := []abciTypes.ValidatorUpdate{validator(1, 30), validator(3, 5), validator(2, 0)}
+	if len(updates) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, updates)
+	}
+	for i := range expected {
+		if !pubKeyEqual(updates[i].PubKey, expected[i].PubKey) || updates[i].Power != expected[i].Power {
+			t.Errorf("update %d: expected %v, got %v", i, expected[i], updates[i])
+		}
+	}
+
+	if updates := strategy.ValidatorUpdates(); len(updates) != 0 {
+		t.Errorf("expected the updates to be cleared, got %v", updates)
+	}
+}
+
+func TestUpdateValidatorsAddedAndRemoved(t *testing.T) {
+	strategy := &Strategy{}
+	strategy.InitChain([]abciTypes.ValidatorUpdate{validator(1, 10)})
+
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(2, 10)})
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(2, 0)})
+	if updates := strategy.ValidatorUpdates(); len(updates) != 0 {
+		t.Errorf("expected no updates, got %v", updates)
+	}
+
+	// a removed validator that comes back is still reported
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(1, 0)})
+	strategy.UpdateValidators([]abciTypes.ValidatorUpdate{validator(1, 10)})
+	updates := strategy.ValidatorUpdates()
+	if len(updates) != 1 || updates[0].Power != 10 {
+		t.Errorf("expected validator 1 with power 10, got %v", updates)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	strategy := &Strategy{}
+	strategy.InitChain([]abciTypes.ValidatorUpdate{validator(1, 10), validator(2, 10)})
+
+	updates := strategy.Diff([]abciTypes.ValidatorUpdate{validator(2, 10), validator(3, 5)})
+	expected := []abciTypes.ValidatorUpdate{validator(3, 5), validator(1, 0)}
+	if len(updates) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, updates)
+	}
+	for i := range expected {
+		if !pubKeyEqual(updates[i].PubKey, expected[i].PubKey) || updates[i].Power != expected[i].Power {
+			t.Errorf("update %d: expected %v, got %v", i, expected[i], updates[i])
+		}
+	}
+}
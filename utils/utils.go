@@ -1,30 +1,72 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
-	"os"
+	"io/ioutil"
 
 	emUtils "github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/core"
+
+	plutoTypes "github.com/zhuzeyu/pluto/types"
 )
 
+// plutoGenesis is the pluto specific section of the genesis file
+type plutoGenesis struct {
+	Pluto *plutoTypes.Params `json:"pluto"`
+}
+
+// defaultGenesisBlob is the genesis used without a genesis file. It funds the
+// account of the keystore file written by init.
+var defaultGenesisBlob = []byte(`{
+	"config": {
+		"chainId": 15,
+		"homesteadBlock": 0,
+		"eip155Block": 0,
+		"eip158Block": 0
+	},
+	"nonce": "0xdeadbeefdeadbeef",
+	"timestamp": "0x00",
+	"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+	"mixhash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+	"difficulty": "0x40",
+	"gasLimit": "0x8000000",
+	"alloc": {
+		"0x7eff122b94897ea5b0e2a9abf47b86337fafebdc": {
+			"balance": "10000000000000000000000000000000000"
+		}
+	}
+}`)
+
 // readGenesis will read the given JSON format genesis file and return
-// the initialized Genesis structure
-func ReadGenesis(genesisPath string) *core.Genesis {
+// the initialized Genesis structure together with the pluto chain parameters.
+// Without a path it returns the default genesis with the default parameters.
+func ReadGenesis(genesisPath string) (*core.Genesis, *plutoTypes.Params) {
 	// Make sure we have a valid genesis JSON
 	//genesisPath := ctx.Args().First()
-	if len(genesisPath) == 0 {
-		emUtils.Fatalf("Must supply path to genesis JSON file")
+	data := defaultGenesisBlob
+	if len(genesisPath) != 0 {
+		var err error
+		if data, err = ioutil.ReadFile(genesisPath); err != nil {
+			emUtils.Fatalf("Failed to read genesis file: %v", err)
+		}
 	}
-	file, err := os.Open(genesisPath)
-	if err != nil {
-		emUtils.Fatalf("Failed to read genesis file: %v", err)
-	}
-	defer file.Close()
 
 	genesis := new(core.Genesis)
-	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(genesis); err != nil {
 		emUtils.Fatalf("invalid genesis file: %v", err)
 	}
-	return genesis
+
+	// core.Genesis ignores unknown fields, so decode the pluto section separately
+	ext := plutoGenesis{Pluto: plutoTypes.DefaultParams()}
+	if err := json.Unmarshal(data, &ext); err != nil {
+		emUtils.Fatalf("invalid pluto genesis section: %v", err)
+	}
+	if ext.Pluto == nil {
+		ext.Pluto = plutoTypes.DefaultParams()
+	}
+	if err := ext.Pluto.Validate(); err != nil {
+		emUtils.Fatalf("invalid pluto genesis section: %v", err)
+	}
+	return genesis, ext.Pluto
 }